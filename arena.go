@@ -0,0 +1,48 @@
+package rbt_orderbook
+
+// number of orders or tree nodes allocated at once when a free list is empty
+const ArenaChunkSize int = 1024
+
+// Free list of orders allocated in chunks
+type orderArena struct {
+	free []*Order
+}
+
+func (this *orderArena) get() *Order {
+	if len(this.free) == 0 {
+		chunk := make([]Order, ArenaChunkSize)
+		for i := range chunk {
+			this.free = append(this.free, &chunk[i])
+		}
+	}
+
+	o := this.free[len(this.free)-1]
+	this.free = this.free[:len(this.free)-1]
+	return o
+}
+
+func (this *orderArena) put(o *Order) {
+	*o = Order{}
+	this.free = append(this.free, o)
+}
+
+// NewOrder returns an empty order from the book arena. Orders created by the book
+// do not allocate in steady state if they are given back with Release.
+func (this *Orderbook) NewOrder() *Order {
+	return this.arena.get()
+}
+
+// Release gives an order back to the book arena once it has left the book: it is
+// filled, cancelled, expired or rejected. The order is reset and must not be used
+// by the caller afterwards, the book may return it from NewOrder again. Orders not
+// created by NewOrder can be released as well.
+func (this *Orderbook) Release(o *Order) error {
+	// grouped orders are detached once they leave the book, waiting bracket
+	// children stay grouped until the entry is filled or leaves the book
+	if o.Limit != nil || o.group != nil || this.GetOrder(o.Id) == o || this.GetStop(o.Id) == o {
+		return ErrOrderInUse
+	}
+
+	this.arena.put(o)
+	return nil
+}
@@ -0,0 +1,268 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+	"math/rand"
+	"testing"
+)
+
+func TestReleaseOrder(t *testing.T) {
+	b := NewOrderbook()
+	o := b.NewOrder()
+	o.Id = 1
+	o.Volume = decimal.NewFromInt(2)
+	b.Add(decimal.NewFromInt(10), o)
+
+	if err := b.Release(o); err != ErrOrderInUse {
+		t.Errorf("resting order should not be released, got %v", err)
+	}
+
+	stop := b.NewOrder()
+	stop.Id = 2
+	stop.Volume = decimal.NewFromInt(1)
+	b.AddStop(decimal.NewFromInt(12), stop)
+	if err := b.Release(stop); err != ErrOrderInUse {
+		t.Errorf("stop order should not be released, got %v", err)
+	}
+
+	b.Cancel(o)
+	if err := b.Release(o); err != nil {
+		t.Errorf("cancelled order should be released, got %v", err)
+	}
+	if o.Id != 0 || o.lots != 0 || o.Limit != nil {
+		t.Errorf("released order should be reset: %+v", o)
+	}
+	if b.NewOrder() != o {
+		t.Errorf("released order should be reused")
+	}
+}
+
+func TestReleaseGroupedOrder(t *testing.T) {
+	b := NewOrderbook()
+	leg1 := &Order{Id: 1, Volume: decimal.NewFromInt(1)}
+	leg2 := &Order{Id: 2, Volume: decimal.NewFromInt(1)}
+	b.Add(decimal.NewFromInt(12), leg1)
+	b.Add(decimal.NewFromInt(13), leg2)
+	b.LinkOCO(OCOOnFill, 1, 2)
+
+	// the other leg is still in the book
+	b.Cancel(leg1)
+	if err := b.Release(leg1); err != nil {
+		t.Errorf("cancelled leg should be released, got %v", err)
+	}
+
+	stop := &Order{Id: 9, Volume: decimal.NewFromInt(1)}
+	b.AddStop(decimal.NewFromInt(8), stop)
+	b.Add(decimal.NewFromInt(14), &Order{Id: 10, Volume: decimal.NewFromInt(1)})
+	if err := b.LinkOCO(OCOOnFill, 9, 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b.CancelById(9)
+	if err := b.Release(stop); err != nil {
+		t.Errorf("cancelled stop leg should be released, got %v", err)
+	}
+
+	bracket := Bracket{
+		Entry:           &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(2)},
+		EntryPrice:      decimal.NewFromInt(10),
+		TakeProfit:      &Order{Id: 4, Volume: decimal.NewFromInt(2)},
+		TakeProfitPrice: decimal.NewFromInt(12),
+		StopLoss:        &Order{Id: 5, Volume: decimal.NewFromInt(2)},
+		StopPrice:       decimal.NewFromInt(8),
+	}
+	b.AddBracket(bracket)
+	if err := b.Release(bracket.TakeProfit); err != ErrOrderInUse {
+		t.Errorf("waiting take-profit should not be released, got %v", err)
+	}
+	if err := b.Release(bracket.StopLoss); err != ErrOrderInUse {
+		t.Errorf("waiting stop-loss should not be released, got %v", err)
+	}
+
+	b.Cancel(bracket.Entry)
+	for _, o := range []*Order{bracket.Entry, bracket.TakeProfit, bracket.StopLoss} {
+		if err := b.Release(o); err != nil {
+			t.Errorf("order %d of a cancelled bracket should be released, got %v", o.Id, err)
+		}
+	}
+
+	// an IOC entry not trading at all never rests
+	bracket.Entry = &Order{Id: 6, BidOrAsk: true, TIF: IOC, Volume: decimal.NewFromInt(2)}
+	bracket.TakeProfit = &Order{Id: 7, Volume: decimal.NewFromInt(2)}
+	bracket.StopLoss = &Order{Id: 8, Volume: decimal.NewFromInt(2)}
+	if _, err := b.AddBracket(bracket); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, o := range []*Order{bracket.Entry, bracket.TakeProfit, bracket.StopLoss} {
+		if err := b.Release(o); err != nil {
+			t.Errorf("order %d of an unfilled bracket should be released, got %v", o.Id, err)
+		}
+	}
+}
+
+func TestRedBlackReuseNodes(t *testing.T) {
+	tr := NewRedBlackBST()
+	for i := 0; i < 100; i += 1 {
+		tr.Put(int64(i), nil)
+	}
+	for i := 0; i < 100; i += 2 {
+		tr.Delete(int64(i))
+	}
+	for i := 100; i < 150; i += 1 {
+		tr.Put(int64(i), nil)
+	}
+
+	if !tr.IsRedBlack() || tr.Size() != 100 {
+		t.Errorf("tree should be balanced with 100 keys, got %d", tr.Size())
+	}
+
+	// walk the linked list both ways
+	n := 0
+	for p := tr.MinPointer(); p != nil; p = p.Next {
+		n++
+	}
+	for p := tr.MaxPointer(); p != nil; p = p.Prev {
+		n++
+	}
+	if n != 200 {
+		t.Errorf("linked list should have 100 nodes, walked %d", n/2)
+	}
+}
+
+// book with resting orders from the arena at random levels around the spread,
+// orders enter through the public ticks and lots entry points
+type steadyBook struct {
+	book   *Orderbook
+	r      *rand.Rand
+	live   []*Order       // resting orders
+	pos    map[*Order]int // indexes of resting orders in live
+	id     int
+	trades []Trade
+}
+
+const steadyLevels = 200
+
+func newSteadyBook(opts ...Option) *steadyBook {
+	b := NewOrderbook(opts...)
+	s := &steadyBook{
+		book: &b,
+		r:    rand.New(rand.NewSource(1)),
+		pos:  make(map[*Order]int, 8*steadyLevels),
+	}
+	for i := 0; i < 4*steadyLevels; i += 1 {
+		s.add()
+	}
+	return s
+}
+
+// adds a resting order of 1 lot, bids are below 1000 ticks, asks are above
+func (this *steadyBook) add() *Order {
+	o := this.book.NewOrder()
+	this.id++
+	o.Id = this.id
+	o.BidOrAsk = this.r.Intn(2) == 0
+
+	price := int64(1001 + this.r.Intn(steadyLevels))
+	if o.BidOrAsk {
+		price = 1000 - int64(this.r.Intn(steadyLevels))
+	}
+
+	if err := this.book.AddTicks(price, 1, o); err != nil {
+		panic(err)
+	}
+	this.pos[o] = len(this.live)
+	this.live = append(this.live, o)
+	return o
+}
+
+// removes the order from live orders and releases it
+func (this *steadyBook) release(o *Order) {
+	k := this.pos[o]
+	last := this.live[len(this.live)-1]
+	this.live[k] = last
+	this.pos[last] = k
+	this.live = this.live[:len(this.live)-1]
+	delete(this.pos, o)
+
+	this.book.Release(o)
+}
+
+// cancels a random resting order
+func (this *steadyBook) cancel() {
+	o := this.live[this.r.Intn(len(this.live))]
+	this.book.Cancel(o)
+	this.release(o)
+}
+
+// crosses the spread with an order of 1 lot from the side opposite to the maker
+// side and releases filled orders
+func (this *steadyBook) match(makerBidOrAsk bool) {
+	o := this.book.NewOrder()
+	this.id++
+	o.Id = this.id
+	o.BidOrAsk = !makerBidOrAsk
+	o.TIF = IOC
+
+	price := int64(0)
+	if o.BidOrAsk {
+		price = 2000
+	}
+
+	// the best opposite order is filled
+	maker := this.book.bestOpposite(o.BidOrAsk).Peek()
+	this.trades, _ = this.book.MatchTicks(price, 1, o, this.trades[:0])
+	if len(this.trades) != 1 || maker.Limit != nil {
+		panic("the best order should be filled")
+	}
+	this.release(maker)
+	this.book.Release(o)
+}
+
+func TestOrderbookSteadyStateAllocs(t *testing.T) {
+	testSteadyStateAllocs(t, newSteadyBook())
+}
+
+func TestOrderbookSteadyStateAllocsLadder(t *testing.T) {
+	testSteadyStateAllocs(t, newSteadyBook(WithPriceLadder(LadderWindow, LadderMaxWindow)))
+}
+
+func testSteadyStateAllocs(t *testing.T, s *steadyBook) {
+
+	// warm up free lists and maps
+	for i := 0; i < 10000; i += 1 {
+		s.add()
+		s.cancel()
+		s.match(s.add().BidOrAsk)
+	}
+
+	if n := testing.AllocsPerRun(1000, func() {
+		s.add()
+		s.cancel()
+	}); n != 0 {
+		t.Errorf("add and cancel should not allocate, got %v allocs", n)
+	}
+
+	if n := testing.AllocsPerRun(1000, func() {
+		s.match(s.add().BidOrAsk)
+	}); n != 0 {
+		t.Errorf("add and match should not allocate, got %v allocs", n)
+	}
+}
+
+func BenchmarkOrderbookSteadyAddCancel(b *testing.B) {
+	s := newSteadyBook()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 1 {
+		s.add()
+		s.cancel()
+	}
+}
+
+func BenchmarkOrderbookSteadyAddMatch(b *testing.B) {
+	s := newSteadyBook()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 1 {
+		s.match(s.add().BidOrAsk)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build debug
+
+package rbt_orderbook
+
+// data structures are validated after every change in debug builds
+const debug = true
@@ -0,0 +1,22 @@
+package rbt_orderbook
+
+import "github.com/shopspring/decimal"
+
+// Incremental update of a single limit, zero orders means the limit was removed
+type Delta struct {
+	Seq      uint64 // book-wide event sequence number
+	BidOrAsk bool
+	Price    decimal.Decimal
+	Lots     int64 // new total volume in lots
+	Orders   int   // new number of orders at the limit
+
+	inst *Instrument
+}
+
+// Volume returns the new total volume at the limit
+func (d Delta) Volume() decimal.Decimal {
+	return d.inst.Volume(d.Lots)
+}
+
+// Callback receiving every limit update as it happens
+type DeltaHandler func(Delta)
@@ -0,0 +1,119 @@
+package rbt_orderbook
+
+import "github.com/shopspring/decimal"
+
+// Aggregated view of a single limit
+type PriceLevel struct {
+	Price  decimal.Decimal
+	Lots   int64 // total volume in lots
+	Orders int   // number of orders at the limit
+
+	inst *Instrument
+}
+
+// Volume returns the total volume at the limit
+func (l PriceLevel) Volume() decimal.Decimal {
+	return l.inst.Volume(l.Lots)
+}
+
+// Depth returns up to n best limits per side with displayed volume only, bids
+// from the highest price and asks from the lowest price
+func (this *Orderbook) Depth(n int) (bids, asks []PriceLevel) {
+	return this.sides(n, false)
+}
+
+// DepthWithHidden returns up to n best limits per side with volume including
+// iceberg reserves
+func (this *Orderbook) DepthWithHidden(n int) (bids, asks []PriceLevel) {
+	return this.sides(n, true)
+}
+
+func (this *Orderbook) sides(n int, hidden bool) (bids, asks []PriceLevel) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if !this.Bids.IsEmpty() {
+		bids = this.depth(n, true, hidden)
+	}
+	if !this.Asks.IsEmpty() {
+		asks = this.depth(n, false, hidden)
+	}
+	return bids, asks
+}
+
+func (this *Orderbook) depth(n int, bidOrAsk, hidden bool) []PriceLevel {
+	side := this.Asks
+	if bidOrAsk {
+		side = this.Bids
+	}
+	// a large n asks for all limits
+	levels := make([]PriceLevel, 0, min(n, side.Size()))
+
+	// bids are walked towards lower prices, asks towards higher
+	for limit := this.best(bidOrAsk); limit != nil && len(levels) < n; limit = this.worse(limit, bidOrAsk) {
+		lots := limit.totalVolume
+		if hidden {
+			lots += limit.hiddenVolume
+		}
+
+		levels = append(levels, PriceLevel{
+			Price:  limit.Price,
+			Lots:   lots,
+			Orders: limit.Size(),
+			inst:   this.inst,
+		})
+	}
+
+	return levels
+}
+
+// Resting order as seen in an order-by-order snapshot
+type OrderEntry struct {
+	Id       int
+	Price    decimal.Decimal
+	Lots     int64 // open displayed volume in lots
+	Hidden   int64 // open iceberg reserve volume in lots
+	BidOrAsk bool
+	Position int // position in the limit queue, 0 is the next to be filled
+
+	inst *Instrument
+}
+
+// Volume returns the open displayed volume of the order
+func (e OrderEntry) Volume() decimal.Decimal {
+	return e.inst.Volume(e.Lots)
+}
+
+// EachOrder calls f for every resting order of the side, limits from the best
+// price and orders in queue order. Iteration stops once f returns false.
+func (this *Orderbook) EachOrder(bidOrAsk bool, f func(e OrderEntry) bool) {
+	stop := false
+	for limit := this.best(bidOrAsk); limit != nil && !stop; limit = this.worse(limit, bidOrAsk) {
+		position := 0
+		limit.Each(func(o *Order) {
+			if stop {
+				return
+			}
+			stop = !f(OrderEntry{
+				Id:       o.Id,
+				Price:    limit.Price,
+				Lots:     o.lots,
+				Hidden:   o.hidden,
+				BidOrAsk: o.BidOrAsk,
+				Position: position,
+				inst:     this.inst,
+			})
+			position++
+		})
+	}
+}
+
+// Orders returns an order-by-order snapshot of the side
+func (this *Orderbook) Orders(bidOrAsk bool) []OrderEntry {
+	var entries []OrderEntry
+	this.EachOrder(bidOrAsk, func(e OrderEntry) bool {
+		entries = append(entries, e)
+		return true
+	})
+	return entries
+}
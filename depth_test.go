@@ -0,0 +1,155 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+	"math"
+	"testing"
+)
+
+func TestDepthEmpty(t *testing.T) {
+	b := NewOrderbook()
+	bids, asks := b.Depth(5)
+	if len(bids) != 0 || len(asks) != 0 {
+		t.Errorf("depth of empty book should be empty")
+	}
+}
+
+func TestDepth(t *testing.T) {
+	b := NewOrderbook()
+	id := 0
+	for i := 1; i <= 5; i += 1 {
+		for j := 0; j < i; j += 1 {
+			id++
+			b.Add(decimal.NewFromInt(int64(10-i)), &Order{Id: id, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+			id++
+			b.Add(decimal.NewFromInt(int64(10+i)), &Order{Id: id, Volume: decimal.NewFromInt(2)})
+		}
+	}
+
+	bids, asks := b.Depth(3)
+	if len(bids) != 3 || len(asks) != 3 {
+		t.Fatalf("there should be 3 levels per side, got %d and %d", len(bids), len(asks))
+	}
+
+	for i := 0; i < 3; i += 1 {
+		if !bids[i].Price.Equal(decimal.NewFromInt(int64(9 - i))) {
+			t.Errorf("bid level %d should be at %d, got %s", i, 9-i, bids[i].Price.String())
+		}
+		if bids[i].Orders != i+1 || !bids[i].Volume().Equal(decimal.NewFromInt(int64(i+1))) {
+			t.Errorf("invalid bid level %d: %+v", i, bids[i])
+		}
+
+		if !asks[i].Price.Equal(decimal.NewFromInt(int64(11 + i))) {
+			t.Errorf("ask level %d should be at %d, got %s", i, 11+i, asks[i].Price.String())
+		}
+		if asks[i].Orders != i+1 || !asks[i].Volume().Equal(decimal.NewFromInt(int64(2*(i+1)))) {
+			t.Errorf("invalid ask level %d: %+v", i, asks[i])
+		}
+	}
+
+	bids, asks = b.Depth(10)
+	if len(bids) != 5 || len(asks) != 5 {
+		t.Errorf("depth should be limited by the number of levels")
+	}
+}
+
+func TestDepthAll(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(9), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(11), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(12), &Order{Id: 3, Volume: decimal.NewFromInt(1)})
+
+	bids, asks := b.Depth(math.MaxInt)
+	if len(bids) != 1 || len(asks) != 2 || cap(asks) != 2 {
+		t.Errorf("depth should have all limits, got %d bids and %d asks", len(bids), len(asks))
+	}
+}
+
+func TestDepthNonPositive(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(9), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(11), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+
+	for _, n := range []int{0, -1} {
+		bids, asks := b.Depth(n)
+		if bids != nil || asks != nil {
+			t.Errorf("depth of %d levels should be empty", n)
+		}
+		bids, asks = b.DepthWithHidden(n)
+		if bids != nil || asks != nil {
+			t.Errorf("depth with hidden of %d levels should be empty", n)
+		}
+	}
+}
+
+func TestOrders(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(9), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(10), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromInt(2)})
+	b.Add(decimal.NewFromInt(10), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(3)})
+	b.Add(decimal.NewFromInt(11), &Order{Id: 4, Volume: decimal.NewFromInt(4)})
+
+	bids := b.Orders(true)
+	if len(bids) != 3 {
+		t.Fatalf("there should be 3 bids, got %d", len(bids))
+	}
+
+	expected := []struct {
+		id       int
+		price    int64
+		volume   int64
+		position int
+	}{
+		{2, 10, 2, 0},
+		{3, 10, 3, 1},
+		{1, 9, 1, 0},
+	}
+	for i, e := range expected {
+		if bids[i].Id != e.id || !bids[i].Price.Equal(decimal.NewFromInt(e.price)) ||
+			!bids[i].Volume().Equal(decimal.NewFromInt(e.volume)) || bids[i].Position != e.position || !bids[i].BidOrAsk {
+			t.Errorf("invalid entry %d: %+v", i, bids[i])
+		}
+	}
+
+	asks := b.Orders(false)
+	if len(asks) != 1 || asks[0].Id != 4 || asks[0].BidOrAsk {
+		t.Errorf("invalid asks: %+v", asks)
+	}
+}
+
+func TestEachOrderStop(t *testing.T) {
+	b := NewOrderbook()
+	for i := 1; i <= 10; i += 1 {
+		b.Add(decimal.NewFromInt(int64(i%3)), &Order{Id: i, Volume: decimal.NewFromInt(1)})
+	}
+
+	n := 0
+	b.EachOrder(false, func(e OrderEntry) bool {
+		n++
+		return n < 4
+	})
+	if n != 4 {
+		t.Errorf("iteration should stop after 4 orders, got %d", n)
+	}
+}
+
+func TestDepthWithHidden(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(9), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(5), Peak: decimal.NewFromInt(2)})
+	b.Add(decimal.NewFromInt(9), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+
+	bids, _ := b.Depth(1)
+	if len(bids) != 1 || !bids[0].Volume().Equal(decimal.NewFromInt(3)) {
+		t.Errorf("depth should show displayed volume only: %+v", bids)
+	}
+
+	bids, _ = b.DepthWithHidden(1)
+	if len(bids) != 1 || !bids[0].Volume().Equal(decimal.NewFromInt(6)) || bids[0].Orders != 2 {
+		t.Errorf("depth should include the reserve: %+v", bids)
+	}
+
+	entries := b.Orders(true)
+	if entries[0].Lots != b.inst.Lots(decimal.NewFromInt(2)) || entries[0].Hidden != b.inst.Lots(decimal.NewFromInt(3)) {
+		t.Errorf("invalid iceberg entry: %+v", entries[0])
+	}
+}
@@ -0,0 +1,27 @@
+package rbt_orderbook
+
+import "errors"
+
+var (
+	ErrDuplicateId       = errors.New("order id already exists in the book")
+	ErrUnknownOrder      = errors.New("order does not exist in the book")
+	ErrUnknownLevel      = errors.New("price level does not exist in the book")
+	ErrEmptySide         = errors.New("side of the book is empty")
+	ErrOutOfRange        = errors.New("keys are out of range")
+	ErrQueueFull         = errors.New("priority queue is full")
+	ErrInvalidVolume     = errors.New("order volume must be positive")
+	ErrInvalidPrice      = errors.New("price is not a multiple of the tick size or out of range")
+	ErrInvalidInstrument = errors.New("tick and lot sizes must be positive")
+	ErrPostOnlyCross     = errors.New("post-only order would take liquidity")
+	ErrAmendCross        = errors.New("amended order would cross the book")
+	ErrCannotFill        = errors.New("order cannot execute its required volume")
+	ErrOrderPegged       = errors.New("pegged order cannot be amended")
+
+	ErrInvalidTrail     = errors.New("trail offset or percent must be positive")
+	ErrUnknownTrailRef  = errors.New("unknown trail reference price")
+	ErrNoReferencePrice = errors.New("there is no reference price")
+
+	ErrOrderGrouped      = errors.New("order already belongs to a group")
+	ErrIncompleteBracket = errors.New("bracket needs entry, take-profit and stop-loss orders")
+	ErrOrderInUse        = errors.New("order is still used by the book")
+)
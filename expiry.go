@@ -0,0 +1,57 @@
+package rbt_orderbook
+
+import (
+	"time"
+)
+
+// Source of the book time, order expiry is checked against it
+type Clock func() time.Time
+
+// SetClock replaces the wall clock of the book, e.g. with a simulated one in backtests
+func (this *Orderbook) SetClock(clock Clock) {
+	this.clock = clock
+}
+
+// Expire cancels resting orders which expired by the current book time and
+// returns their ids. Expired orders are also removed before every order entering
+// the book.
+func (this *Orderbook) Expire() []int {
+	ids := this.expire()
+	this.trigger()
+	return ids
+}
+
+func (this *Orderbook) expire() []int {
+	if this.expiries.IsEmpty() {
+		return nil
+	}
+
+	var ids []int
+	now := this.clock().UnixNano()
+	for o := this.expiries.pop(now); o != nil; o = this.expiries.pop(now) {
+		o.expiring = false
+		this.cancel(o)
+		ids = append(ids, o.Id)
+	}
+	return ids
+}
+
+// schedules expiry of an order entering the book
+func (this *Orderbook) scheduleExpiry(o *Order) {
+	if o.Expiry.IsZero() {
+		return
+	}
+
+	o.expiring = true
+	o.expirySlot = this.expiries.insert(o, o.Expiry.UnixNano())
+}
+
+// unschedules expiry of an order leaving the book
+func (this *Orderbook) unscheduleExpiry(o *Order) {
+	if !o.expiring {
+		return
+	}
+
+	o.expiring = false
+	this.expiries.delete(o.expirySlot)
+}
@@ -0,0 +1,52 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestExpire(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewOrderbook()
+	b.SetClock(func() time.Time { return now })
+
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(1), Expiry: now.Add(time.Second)})
+	b.Add(decimal.NewFromInt(11), &Order{Id: 2, Volume: decimal.NewFromInt(1), Expiry: now.Add(2 * time.Second)})
+	b.Add(decimal.NewFromInt(11), &Order{Id: 3, Volume: decimal.NewFromInt(1)})
+
+	if ids := b.Expire(); len(ids) != 0 {
+		t.Errorf("nothing should expire yet: %+v", ids)
+	}
+
+	now = now.Add(time.Second)
+	ids := b.Expire()
+	if len(ids) != 1 || ids[0] != 1 || b.GetOrder(1) != nil || b.ALength() != 1 {
+		t.Errorf("first order should expire: %+v", ids)
+	}
+
+	// expired orders are removed before matching
+	now = now.Add(time.Hour)
+	trades, _ := b.Match(decimal.NewFromInt(11), &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	if len(trades) != 1 || trades[0].MakerId != 3 {
+		t.Errorf("expired order should not trade: %+v", trades)
+	}
+}
+
+func TestExpireLeftOrders(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewOrderbook()
+	b.SetClock(func() time.Time { return now })
+
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(1), Expiry: now.Add(time.Second)})
+	b.Add(decimal.NewFromInt(10), &Order{Id: 2, Volume: decimal.NewFromInt(1), Expiry: now.Add(time.Second)})
+	b.Add(decimal.NewFromInt(10), &Order{Id: 3, Volume: decimal.NewFromInt(1), Expiry: now.Add(time.Second)})
+	b.CancelById(2)
+	b.Match(decimal.NewFromInt(10), &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+
+	now = now.Add(time.Second)
+	ids := b.Expire()
+	if len(ids) != 1 || ids[0] != 3 || b.ALength() != 0 {
+		t.Errorf("only the resting order should expire: %+v", ids)
+	}
+}
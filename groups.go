@@ -0,0 +1,219 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// What fill of an OCO group leg cancels the other legs
+type OCOPolicy int
+
+const (
+	OCOOnFill        OCOPolicy = iota // the leg is filled completely
+	OCOOnPartialFill                  // the leg is filled at least partially
+)
+
+// Entry order with take-profit and stop-loss orders entering the book once the
+// entry is filled completely. The take-profit enters as a limit order, the
+// stop-loss as a stop order, and they are linked into an OCO group.
+type Bracket struct {
+	Entry           *Order
+	EntryPrice      decimal.Decimal
+	TakeProfit      *Order
+	TakeProfitPrice decimal.Decimal
+	StopLoss        *Order
+	StopPrice       decimal.Decimal
+}
+
+// Orders linked by OCO or bracket. A leg stays in the group while it rests in
+// the book or waits as a stop order or as a bracket child.
+type orderGroup struct {
+	legs    []*Order
+	policy  OCOPolicy
+	done    bool     // the legs are cancelled or the children are activated
+	bracket *Bracket // children of a bracket entry, nil for OCO groups
+}
+
+// LinkOCO links resting or untriggered stop orders into a group in which a fill
+// of any leg cancels the other legs
+func (this *Orderbook) LinkOCO(policy OCOPolicy, ids ...int) error {
+	legs := make([]*Order, 0, len(ids))
+	for _, id := range ids {
+		o := this.GetOrder(id)
+		if o == nil {
+			o = this.GetStop(id)
+		}
+		if o == nil {
+			return ErrUnknownOrder
+		}
+		if o.group != nil {
+			return ErrOrderGrouped
+		}
+		legs = append(legs, o)
+	}
+
+	this.link(policy, legs...)
+	return nil
+}
+
+func (this *Orderbook) link(policy OCOPolicy, legs ...*Order) {
+	g := &orderGroup{
+		legs:   legs,
+		policy: policy,
+	}
+	for _, o := range legs {
+		o.group = g
+	}
+}
+
+// AddBracket matches the bracket entry as a limit order and activates the
+// take-profit and stop-loss orders once the entry is filled. The bracket is
+// validated before the entry enters the book, the entry is not grouped if it
+// is rejected.
+func (this *Orderbook) AddBracket(b Bracket) ([]Trade, error) {
+	if err := this.checkBracket(&b); err != nil {
+		return nil, err
+	}
+
+	// the children are grouped with the entry until it is filled
+	g := &orderGroup{
+		legs:    []*Order{b.Entry, b.TakeProfit, b.StopLoss},
+		bracket: &b,
+	}
+	for _, o := range g.legs {
+		o.group = g
+	}
+
+	trades, err := this.Match(b.EntryPrice, b.Entry)
+	if err != nil {
+		for _, o := range g.legs {
+			o.group = nil
+		}
+	}
+	return trades, err
+}
+
+func (this *Orderbook) checkBracket(b *Bracket) error {
+	legs := []*Order{b.Entry, b.TakeProfit, b.StopLoss}
+	for i, o := range legs {
+		if o == nil {
+			return ErrIncompleteBracket
+		}
+		if o.group != nil {
+			return ErrOrderGrouped
+		}
+		if this.GetOrder(o.Id) != nil || this.GetStop(o.Id) != nil {
+			return ErrDuplicateId
+		}
+		for _, other := range legs[:i] {
+			if other.Id == o.Id {
+				return ErrDuplicateId
+			}
+		}
+		if lots, err := this.inst.orderLots(o.Volume); err != nil || lots <= 0 {
+			return ErrInvalidVolume
+		}
+	}
+
+	for _, price := range []decimal.Decimal{b.EntryPrice, b.TakeProfitPrice, b.StopPrice} {
+		if _, err := this.inst.ExactTicks(price); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cancels other legs of the OCO groups or activates the bracket children of
+// the filled orders
+func (this *Orderbook) settleGroups() {
+	// activated children can be filled and appended while iterating
+	for i := 0; i < len(this.filled); i += 1 {
+		o := this.filled[i]
+		g := o.group
+		full := o.lots+o.hidden <= 0
+		if g.done || !full && (g.bracket != nil || g.policy == OCOOnFill) {
+			continue
+		}
+		g.done = true
+
+		if g.bracket != nil {
+			this.activate(g.bracket)
+			continue
+		}
+
+		for _, leg := range g.legs {
+			if leg == o {
+				continue
+			}
+
+			if this.GetOrder(leg.Id) == leg {
+				this.cancel(leg)
+			} else if this.GetStop(leg.Id) == leg {
+				this.cancelStop(leg)
+			}
+		}
+	}
+	this.filled = this.filled[:0]
+
+	// legs are detached once the fills are settled, unless they are back in the book
+	for _, o := range this.left {
+		if o.group != nil && this.GetOrder(o.Id) != o && this.GetStop(o.Id) != o {
+			this.detach(o)
+		}
+	}
+	this.left = this.left[:0]
+}
+
+// remembers a grouped order leaving the book to detach it once the groups are settled
+func (this *Orderbook) leave(o *Order) {
+	if o.group != nil {
+		this.left = append(this.left, o)
+	}
+}
+
+// removes the order from its group, the waiting children of a bracket entry
+// leaving the book unfilled are detached as well
+func (this *Orderbook) detach(o *Order) {
+	g := o.group
+	o.group = nil
+	for i, leg := range g.legs {
+		if leg == o {
+			g.legs = append(g.legs[:i], g.legs[i+1:]...)
+			break
+		}
+	}
+
+	if g.bracket != nil && !g.done && o == g.bracket.Entry {
+		g.done = true
+		for _, leg := range g.legs {
+			if leg.group == g {
+				leg.group = nil
+			}
+		}
+		g.legs = nil
+	}
+}
+
+// enters the take-profit and stop-loss orders of a filled bracket entry, the
+// rejected ones are reported to reject handlers
+func (this *Orderbook) activate(b *Bracket) {
+	tp, sl := b.TakeProfit, b.StopLoss
+	this.link(OCOOnFill, tp, sl)
+
+	err := this.admit(tp)
+	if err == nil {
+		_, err = this.match(this.inst.Ticks(b.TakeProfitPrice), tp, nil)
+	}
+	if err != nil {
+		this.reject(tp, err)
+		this.leave(tp)
+	}
+
+	err = this.admit(sl)
+	if err == nil {
+		err = this.pushStop(this.inst.Ticks(b.StopPrice), sl)
+	}
+	if err != nil {
+		this.reject(sl, err)
+		this.leave(sl)
+	}
+}
@@ -0,0 +1,159 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestOCOOnFill(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(12), &Order{Id: 1, Volume: decimal.NewFromInt(2)})
+	b.AddStop(decimal.NewFromInt(9), &Order{Id: 2, Volume: decimal.NewFromInt(2)})
+	if err := b.LinkOCO(OCOOnFill, 1, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b.Match(decimal.NewFromInt(12), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	if b.GetStop(2) == nil {
+		t.Errorf("partial fill should not cancel the other leg")
+	}
+
+	b.Match(decimal.NewFromInt(12), &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	if b.GetStop(2) != nil {
+		t.Errorf("full fill should cancel the other leg")
+	}
+}
+
+func TestOCOOnPartialFill(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(12), &Order{Id: 1, Volume: decimal.NewFromInt(2)})
+	b.Add(decimal.NewFromInt(13), &Order{Id: 2, Volume: decimal.NewFromInt(2)})
+	b.LinkOCO(OCOOnPartialFill, 1, 2)
+
+	b.Match(decimal.NewFromInt(12), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	if b.GetOrder(1) == nil || b.GetOrder(2) != nil || b.ALength() != 1 {
+		t.Errorf("partial fill should cancel the other leg")
+	}
+
+	if err := b.LinkOCO(OCOOnFill, 5, 6); err != ErrUnknownOrder {
+		t.Errorf("expected ErrUnknownOrder, got %v", err)
+	}
+	b.Add(decimal.NewFromInt(13), &Order{Id: 5, Volume: decimal.NewFromInt(2)})
+	if err := b.LinkOCO(OCOOnFill, 1, 5); err != ErrOrderGrouped {
+		t.Errorf("expected ErrOrderGrouped, got %v", err)
+	}
+}
+
+func TestBracket(t *testing.T) {
+	b := NewOrderbook()
+	bracket := Bracket{
+		Entry:           &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(2)},
+		EntryPrice:      decimal.NewFromInt(10),
+		TakeProfit:      &Order{Id: 2, Volume: decimal.NewFromInt(2)},
+		TakeProfitPrice: decimal.NewFromInt(12),
+		StopLoss:        &Order{Id: 3, Volume: decimal.NewFromInt(2)},
+		StopPrice:       decimal.NewFromInt(8),
+	}
+	if _, err := b.AddBracket(bracket); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b.Match(decimal.NewFromInt(10), &Order{Id: 4, Volume: decimal.NewFromInt(1)})
+	if b.GetOrder(2) != nil || b.GetStop(3) != nil {
+		t.Errorf("children should wait for the entry to be filled")
+	}
+
+	b.Match(decimal.NewFromInt(10), &Order{Id: 5, Volume: decimal.NewFromInt(1)})
+	if b.GetOrder(2) == nil || b.GetStop(3) == nil {
+		t.Fatalf("children should be activated")
+	}
+
+	b.Match(decimal.NewFromInt(12), &Order{Id: 6, BidOrAsk: true, Volume: decimal.NewFromInt(2)})
+	if b.GetOrder(2) != nil || b.GetStop(3) != nil {
+		t.Errorf("take-profit fill should cancel the stop-loss")
+	}
+}
+
+func TestBracketInvalid(t *testing.T) {
+	b := NewOrderbook()
+	bracket := func() Bracket {
+		return Bracket{
+			Entry:           &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(2)},
+			EntryPrice:      decimal.NewFromInt(10),
+			TakeProfit:      &Order{Id: 2, Volume: decimal.NewFromInt(2)},
+			TakeProfitPrice: decimal.NewFromInt(12),
+			StopLoss:        &Order{Id: 3, Volume: decimal.NewFromInt(2)},
+			StopPrice:       decimal.NewFromInt(8),
+		}
+	}
+
+	br := bracket()
+	br.StopLoss = nil
+	if _, err := b.AddBracket(br); err != ErrIncompleteBracket {
+		t.Errorf("expected ErrIncompleteBracket, got %v", err)
+	}
+
+	br = bracket()
+	br.TakeProfit.Id = 3
+	if _, err := b.AddBracket(br); err != ErrDuplicateId {
+		t.Errorf("expected ErrDuplicateId, got %v", err)
+	}
+
+	br = bracket()
+	br.StopPrice = decimal.RequireFromString("8.5")
+	b = NewOrderbookWithInstrument(Instrument{TickSize: decimal.NewFromInt(1), LotSize: decimal.NewFromInt(1)})
+	if _, err := b.AddBracket(br); err != ErrInvalidPrice || br.Entry.group != nil || b.GetOrder(1) != nil {
+		t.Errorf("expected ErrInvalidPrice without side effects, got %v", err)
+	}
+
+	b = NewOrderbook()
+	br = bracket()
+	b.Add(decimal.NewFromInt(20), &Order{Id: 7, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(21), &Order{Id: 8, Volume: decimal.NewFromInt(1)})
+	b.LinkOCO(OCOOnFill, 7, 8)
+	br.Entry = b.GetOrder(7)
+	if _, err := b.AddBracket(br); err != ErrOrderGrouped {
+		t.Errorf("expected ErrOrderGrouped, got %v", err)
+	}
+
+	b.Add(decimal.NewFromInt(9), &Order{Id: 9, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	br = bracket()
+	br.Entry.BidOrAsk = false
+	br.Entry.PostOnly = true
+	br.EntryPrice = decimal.NewFromInt(9)
+	if _, err := b.AddBracket(br); err != ErrPostOnlyCross {
+		t.Errorf("expected ErrPostOnlyCross, got %v", err)
+	}
+	if br.Entry.group != nil {
+		t.Errorf("rejected entry should not be grouped")
+	}
+}
+
+func TestBracketRejectedChild(t *testing.T) {
+	b := NewOrderbook()
+	var rejected []int
+	b.OnReject(func(o *Order, err error) {
+		if err != ErrCannotFill {
+			t.Errorf("expected ErrCannotFill, got %v", err)
+		}
+		rejected = append(rejected, o.Id)
+	})
+
+	bracket := Bracket{
+		Entry:           &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(2)},
+		EntryPrice:      decimal.NewFromInt(10),
+		TakeProfit:      &Order{Id: 2, TIF: FOK, Volume: decimal.NewFromInt(2)},
+		TakeProfitPrice: decimal.NewFromInt(12),
+		StopLoss:        &Order{Id: 3, Volume: decimal.NewFromInt(2)},
+		StopPrice:       decimal.NewFromInt(8),
+	}
+	b.AddBracket(bracket)
+	b.Match(decimal.NewFromInt(10), &Order{Id: 5, Volume: decimal.NewFromInt(2)})
+
+	if len(rejected) != 1 || rejected[0] != 2 {
+		t.Errorf("take-profit should be rejected, got %v", rejected)
+	}
+	if b.GetOrder(2) != nil || b.GetStop(3) == nil {
+		t.Errorf("stop-loss should be activated without the take-profit")
+	}
+}
@@ -1,10 +1,8 @@
 package rbt_orderbook
 
-import "github.com/shopspring/decimal"
-
 // Indexed mininum oriented Priority Queue
 type indexMinPQ struct {
-	keys         []decimal.Decimal
+	keys         []int64
 	index2offset []int
 	offset2index []int
 	n            int
@@ -12,7 +10,7 @@ type indexMinPQ struct {
 
 func NewIndexMinPQ(size int) indexMinPQ {
 	return indexMinPQ{
-		keys:         make([]decimal.Decimal, size+1),
+		keys:         make([]int64, size+1),
 		index2offset: make([]int, size+1),
 		offset2index: make([]int, size+1),
 	}
@@ -26,7 +24,7 @@ func (pq *indexMinPQ) IsEmpty() bool {
 	return pq.n == 0
 }
 
-func (pq *indexMinPQ) Insert(i int, key decimal.Decimal) {
+func (pq *indexMinPQ) Insert(i int, key int64) {
 	pq.checkIndex(i)
 
 	if pq.index2offset[i] > 0 {
@@ -45,7 +43,7 @@ func (pq *indexMinPQ) Insert(i int, key decimal.Decimal) {
 	pq.swim(i)
 }
 
-func (pq *indexMinPQ) Change(i int, key decimal.Decimal) {
+func (pq *indexMinPQ) Change(i int, key int64) {
 	pq.checkIndex(i)
 
 	offset := pq.index2offset[i]
@@ -58,9 +56,9 @@ func (pq *indexMinPQ) Change(i int, key decimal.Decimal) {
 	pq.keys[offset] = key
 
 	// restore order
-	if key.GreaterThan(k) {
+	if key > k {
 		pq.sink(i)
-	} else if key.LessThan(k) {
+	} else if key < k {
 		pq.swim(i)
 	}
 }
@@ -79,6 +77,14 @@ func (pq *indexMinPQ) Delete(i int) {
 		panic("invalid index")
 	}
 
+	if offset == pq.n {
+		// the last key, nothing to restore
+		pq.offset2index[pq.n] = 0
+		pq.index2offset[i] = 0
+		pq.n--
+		return
+	}
+
 	// replace key with the lask key
 	pq.keys[offset] = pq.keys[pq.n]
 
@@ -93,11 +99,25 @@ func (pq *indexMinPQ) Delete(i int) {
 
 	pq.n--
 
-	// restore order
+	// restore order, the last key can be less or greater than the removed one
 	pq.sink(lastkeyindex)
+	pq.swim(lastkeyindex)
+}
+
+// reallocates the queue to hold up to size keys with indexes less than size
+func (pq *indexMinPQ) grow(size int) {
+	keys := make([]int64, size+1)
+	index2offset := make([]int, size+1)
+	offset2index := make([]int, size+1)
+	copy(keys, pq.keys)
+	copy(index2offset, pq.index2offset)
+	copy(offset2index, pq.offset2index)
+	pq.keys = keys
+	pq.index2offset = index2offset
+	pq.offset2index = offset2index
 }
 
-func (pq *indexMinPQ) Top() decimal.Decimal {
+func (pq *indexMinPQ) Top() int64 {
 	if pq.IsEmpty() {
 		panic("pq is empty")
 	}
@@ -130,7 +150,7 @@ func (pq *indexMinPQ) checkIndex(i int) {
 
 func (pq *indexMinPQ) swim(i int) {
 	k := pq.index2offset[i]
-	for k > 1 && pq.keys[k].LessThan(pq.keys[k/2]) {
+	for k > 1 && pq.keys[k] < pq.keys[k/2] {
 		// swap keys
 		pq.keys[k], pq.keys[k/2] = pq.keys[k/2], pq.keys[k]
 
@@ -150,11 +170,11 @@ func (pq *indexMinPQ) sink(i int) {
 		c := 2 * k
 
 		// select minimum of two children
-		if c < pq.n && pq.keys[c+1].LessThan(pq.keys[c]) {
+		if c < pq.n && pq.keys[c+1] < pq.keys[c] {
 			c++
 		}
 
-		if pq.keys[k].GreaterThan(pq.keys[c]) {
+		if pq.keys[k] > pq.keys[c] {
 			// swap keys
 			pq.keys[k], pq.keys[c] = pq.keys[c], pq.keys[k]
 
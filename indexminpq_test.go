@@ -9,50 +9,37 @@ import (
 
 func TestIndexMinPQOne(t *testing.T) {
 	minpq := NewIndexMinPQ(10)
-	minpq.Insert(0, decimal.NewFromFloat(5.0))
+	minpq.Insert(0, 5)
 	res := minpq.Top()
 
-	expected := decimal.NewFromFloat(5.0)
-	if res.Cmp(expected) != 0 {
+	var expected int64 = 5
+	if res != expected {
 		t.Errorf("actual %+v != expected %+v", res, expected)
 	}
 }
 
 func TestIndexMinPQTwo(t *testing.T) {
 	minpq := NewIndexMinPQ(10)
-	minpq.Insert(0, decimal.NewFromFloat(6.0))
-	minpq.Insert(1, decimal.NewFromFloat(5.0))
+	minpq.Insert(0, 6)
+	minpq.Insert(1, 5)
 
-	res := [2]decimal.Decimal{}
+	res := [2]int64{}
 	res[0] = minpq.Top()
 	minpq.DelTop()
 	res[1] = minpq.Top()
 
-	exp := [2]decimal.Decimal{decimal.NewFromFloat(5.0), decimal.NewFromFloat(6.0)}
-	if len(res) != len(exp) {
+	exp := [2]int64{5, 6}
+	if res != exp {
 		t.Errorf("actual %+v != expected %+v", res, exp)
 	}
-	resMap := make(map[decimal.Decimal]decimal.Decimal)
-	expMap := make(map[decimal.Decimal]decimal.Decimal)
-	for _, v := range res {
-		resMap[v] = v
-	}
-	for _, v := range exp {
-		expMap[v] = v
-	}
-	for k, v := range resMap {
-		if v.Equal(expMap[k]) {
-			t.Errorf("actual resMap:%+v != expected expMap:%+v", resMap, expMap)
-		}
-	}
 }
 
 func TestIndexMinPQThree(t *testing.T) {
 	minpq := NewIndexMinPQ(10)
-	minpq.Insert(0, decimal.NewFromFloat(6.0))
-	minpq.Insert(1, decimal.NewFromFloat(5.0))
-	minpq.Insert(2, decimal.NewFromFloat(4.0))
-	res := [3]decimal.Decimal{}
+	minpq.Insert(0, 6)
+	minpq.Insert(1, 5)
+	minpq.Insert(2, 4)
+	res := [3]int64{}
 	res[0] = minpq.Top()
 	minpq.DelTop()
 	res[1] = minpq.Top()
@@ -60,19 +47,9 @@ func TestIndexMinPQThree(t *testing.T) {
 	res[2] = minpq.Top()
 	minpq.DelTop()
 
-	exp := [3]decimal.Decimal{decimal.NewFromFloat(4.0), decimal.NewFromFloat(5.0), decimal.NewFromFloat(6.0)}
-	resMap := make(map[decimal.Decimal]decimal.Decimal)
-	expMap := make(map[decimal.Decimal]decimal.Decimal)
-	for _, v := range res {
-		resMap[v] = v
-	}
-	for _, v := range exp {
-		expMap[v] = v
-	}
-	for k, v := range resMap {
-		if v.Equal(expMap[k]) {
-			t.Errorf("actual resMap:%+v != expected expMap:%+v", resMap, expMap)
-		}
+	exp := [3]int64{4, 5, 6}
+	if res != exp {
+		t.Errorf("actual %+v != expected %+v", res, exp)
 	}
 
 	if !minpq.IsEmpty() {
@@ -88,10 +65,10 @@ func TestIndexMinPQRandom(t *testing.T) {
 		if minpq.Size() == 100 {
 			emptyindex = minpq.DelTop()
 		}
-		minpq.Insert(emptyindex, decimal.NewFromInt(int64(rand.Intn(100))))
+		minpq.Insert(emptyindex, int64(rand.Intn(100)))
 	}
 
-	res := make([]decimal.Decimal, 100)
+	res := make([]int64, 100)
 	for i := range res {
 		res[i] = minpq.Top()
 		minpq.DelTop()
@@ -102,7 +79,7 @@ func TestIndexMinPQRandom(t *testing.T) {
 	}
 
 	for i := 1; i < 100; i += 1 {
-		if res[i].LessThan(res[i-1]) {
+		if res[i] < res[i-1] {
 			t.Errorf("invalid order")
 		}
 	}
@@ -112,9 +89,9 @@ func BenchmarkIndexMinPQLimitedRandomInsertWithCaching(b *testing.B) {
 	pq := NewIndexMinPQ(10000)
 
 	// maximum number of levels in average is 10k
-	limitslist := make([]decimal.Decimal, 10000)
+	limitslist := make([]int64, 10000)
 	for i := range limitslist {
-		limitslist[i] = decimal.NewFromFloat(rand.Float64())
+		limitslist[i] = rand.Int63n(1e8)
 	}
 
 	// preallocate empty orders
@@ -126,7 +103,7 @@ func BenchmarkIndexMinPQLimitedRandomInsertWithCaching(b *testing.B) {
 	// measure insertion time
 	b.ResetTimer()
 
-	limitscache := make(map[decimal.Decimal]*LimitOrder)
+	limitscache := make(map[int64]*LimitOrder)
 	for i := 0; i < b.N; i += 1 {
 		// create a new order
 		o := orders[i]
@@ -146,7 +123,7 @@ func BenchmarkIndexMinPQLimitedRandomInsertWithCaching(b *testing.B) {
 			limitscache[price].Enqueue(o)
 		} else {
 			// new limit
-			l := NewLimitOrder(price)
+			l := newLimitOrder(&DefaultInstrument, price)
 			l.Enqueue(o)
 
 			// caching limit
@@ -157,3 +134,33 @@ func BenchmarkIndexMinPQLimitedRandomInsertWithCaching(b *testing.B) {
 		}
 	}
 }
+
+func TestIndexMinPQDeleteRestoresOrder(t *testing.T) {
+	minpq := NewIndexMinPQ(10)
+	keys := []int64{1, 10, 2, 11, 12, 3, 4}
+	for i, k := range keys {
+		minpq.Insert(i, k)
+	}
+
+	// the last key moves under a greater parent
+	minpq.Delete(3)
+	minpq.Delete(6)
+	minpq.Change(0, 20)
+
+	res := []int64{}
+	for !minpq.IsEmpty() {
+		res = append(res, minpq.Top())
+		minpq.DelTop()
+	}
+
+	exp := []int64{2, 3, 10, 12, 20}
+	if len(res) != len(exp) {
+		t.Fatalf("actual %+v != expected %+v", res, exp)
+	}
+	for i := range exp {
+		if res[i] != exp[i] {
+			t.Errorf("actual %+v != expected %+v", res, exp)
+			break
+		}
+	}
+}
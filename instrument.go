@@ -0,0 +1,115 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+	"math"
+)
+
+// Price and volume granularity of a traded instrument. The book stores prices
+// and volumes as int64 numbers of ticks and lots, decimals are used at the API only.
+type Instrument struct {
+	TickSize decimal.Decimal
+	LotSize  decimal.Decimal
+}
+
+// 1e-8 tick and lot sizes, fits prices and volumes up to ~9.2e10, larger ones
+// are rejected
+var DefaultInstrument = NewInstrument(decimal.New(1, -8), decimal.New(1, -8))
+
+// bounds of int64 ticks and lots
+var (
+	maxUnits = decimal.NewFromInt(math.MaxInt64)
+	minUnits = decimal.NewFromInt(math.MinInt64)
+)
+
+// NewInstrument panics with ErrInvalidInstrument if a size is not positive
+func NewInstrument(tickSize, lotSize decimal.Decimal) Instrument {
+	inst := Instrument{
+		TickSize: tickSize,
+		LotSize:  lotSize,
+	}
+	inst.mustValidate()
+	return inst
+}
+
+// Validate checks that the tick and lot sizes are positive
+func (this Instrument) Validate() error {
+	if !this.TickSize.IsPositive() || !this.LotSize.IsPositive() {
+		return ErrInvalidInstrument
+	}
+	return nil
+}
+
+func (this Instrument) mustValidate() {
+	if err := this.Validate(); err != nil {
+		panic(err)
+	}
+}
+
+// converts a quotient to int64, ok is false if it does not fit
+func units(q decimal.Decimal) (int64, bool) {
+	if q.GreaterThan(maxUnits) || q.LessThan(minUnits) {
+		return 0, false
+	}
+	return q.IntPart(), true
+}
+
+// converts a number of units to int64 clamping it to the int64 range
+func clampUnits(q decimal.Decimal) int64 {
+	if n, ok := units(q); ok {
+		return n
+	}
+	if q.IsPositive() {
+		return math.MaxInt64
+	}
+	return math.MinInt64
+}
+
+// converts a price to a number of ticks, truncating the remainder and clamping
+// it to the int64 range
+func (this *Instrument) Ticks(price decimal.Decimal) int64 {
+	ticks, _ := price.QuoRem(this.TickSize, 0)
+	return clampUnits(ticks)
+}
+
+// converts a price to a number of ticks, ErrInvalidPrice if it is off the tick
+// grid or out of the int64 range
+func (this *Instrument) ExactTicks(price decimal.Decimal) (int64, error) {
+	q, rem := price.QuoRem(this.TickSize, 0)
+	if !rem.IsZero() {
+		return 0, ErrInvalidPrice
+	}
+	ticks, ok := units(q)
+	if !ok {
+		return 0, ErrInvalidPrice
+	}
+	return ticks, nil
+}
+
+// converts a number of ticks to a price
+func (this *Instrument) Price(ticks int64) decimal.Decimal {
+	return this.TickSize.Mul(decimal.NewFromInt(ticks))
+}
+
+// converts a volume to a number of lots, truncating the remainder and clamping
+// it to the int64 range
+func (this *Instrument) Lots(volume decimal.Decimal) int64 {
+	lots, _ := volume.QuoRem(this.LotSize, 0)
+	return clampUnits(lots)
+}
+
+// converts an order volume to a number of lots, truncating the remainder,
+// ErrInvalidVolume if it is out of the int64 range
+func (this *Instrument) orderLots(volume decimal.Decimal) (int64, error) {
+	q, _ := volume.QuoRem(this.LotSize, 0)
+	lots, ok := units(q)
+	if !ok {
+		return 0, ErrInvalidVolume
+	}
+	return lots, nil
+}
+
+// converts a number of lots to a volume
+func (this *Instrument) Volume(lots int64) decimal.Decimal {
+	return this.LotSize.Mul(decimal.NewFromInt(lots))
+}
@@ -0,0 +1,180 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestInstrumentConversion(t *testing.T) {
+	inst := NewInstrument(decimal.NewFromFloat(0.5), decimal.NewFromFloat(0.001))
+
+	if inst.Ticks(decimal.NewFromFloat(10.0)) != 20 {
+		t.Errorf("10.0 should be 20 ticks, got %d", inst.Ticks(decimal.NewFromFloat(10.0)))
+	}
+	if inst.Ticks(decimal.NewFromFloat(10.75)) != 21 {
+		t.Errorf("off-tick price should be truncated, got %d", inst.Ticks(decimal.NewFromFloat(10.75)))
+	}
+	if !inst.Price(21).Equal(decimal.NewFromFloat(10.5)) {
+		t.Errorf("21 ticks should be 10.5, got %s", inst.Price(21).String())
+	}
+	if inst.Lots(decimal.NewFromFloat(1.2345)) != 1234 {
+		t.Errorf("off-lot volume should be truncated, got %d", inst.Lots(decimal.NewFromFloat(1.2345)))
+	}
+	if !inst.Volume(1234).Equal(decimal.NewFromFloat(1.234)) {
+		t.Errorf("1234 lots should be 1.234, got %s", inst.Volume(1234).String())
+	}
+}
+
+func TestOrderbookWithInstrument(t *testing.T) {
+	b := NewOrderbookWithInstrument(NewInstrument(decimal.NewFromFloat(0.5), decimal.NewFromInt(1)))
+	b.Add(decimal.NewFromFloat(10.0), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromFloat(2.0)})
+	b.Add(decimal.NewFromFloat(10.5), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromFloat(1.5)})
+
+	if b.BLength() != 2 {
+		t.Errorf("orders should rest at 2 ticks")
+	}
+	if !b.GetVolumeAtBidLimit(decimal.NewFromFloat(10.5)).Equal(decimal.NewFromInt(1)) {
+		t.Errorf("volume should be counted in whole lots: %s", b.GetVolumeAtBidLimit(decimal.NewFromFloat(10.5)).String())
+	}
+	if !b.GetBestBid().Equal(decimal.NewFromFloat(10.5)) {
+		t.Errorf("best bid should be 10.5")
+	}
+}
+
+func TestOrderbookInvalidPrice(t *testing.T) {
+	b := NewOrderbookWithInstrument(NewInstrument(decimal.NewFromFloat(0.01), decimal.NewFromInt(1)))
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+
+	// an ask below one tick above the bid would trade under its own limit if truncated
+	if _, err := b.Match(decimal.NewFromFloat(10.005), &Order{Id: 2, Volume: decimal.NewFromInt(1)}); err != ErrInvalidPrice {
+		t.Errorf("off-grid price should be rejected, got %v", err)
+	}
+	if err := b.Add(decimal.NewFromFloat(10.005), &Order{Id: 3, Volume: decimal.NewFromInt(1)}); err != ErrInvalidPrice {
+		t.Errorf("off-grid price should be rejected, got %v", err)
+	}
+	if err := b.Amend(1, decimal.NewFromFloat(9.999), decimal.NewFromInt(1)); err != ErrInvalidPrice {
+		t.Errorf("off-grid amend should be rejected, got %v", err)
+	}
+	if err := b.AddStop(decimal.NewFromFloat(9.995), &Order{Id: 4, Volume: decimal.NewFromInt(1)}); err != ErrInvalidPrice {
+		t.Errorf("off-grid stop should be rejected, got %v", err)
+	}
+	if err := b.DeleteBidLimit(decimal.NewFromFloat(10.001)); err != ErrInvalidPrice {
+		t.Errorf("off-grid limit should be rejected, got %v", err)
+	}
+	if b.ALength() != 0 || b.BLength() != 1 || b.GetStop(4) != nil {
+		t.Errorf("rejected orders should not change the book")
+	}
+}
+
+func TestOrderbookInvalidVolume(t *testing.T) {
+	b := NewOrderbookWithInstrument(NewInstrument(decimal.NewFromInt(1), decimal.NewFromFloat(0.1)))
+	if err := b.Add(decimal.NewFromInt(10), &Order{Id: 1, BidOrAsk: true}); err != ErrInvalidVolume {
+		t.Errorf("order without volume should be rejected, got %v", err)
+	}
+	if _, err := b.Match(decimal.NewFromInt(10), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromFloat(0.05)}); err != ErrInvalidVolume {
+		t.Errorf("order below one lot should be rejected, got %v", err)
+	}
+	if err := b.AddPegged(Peg{Ref: PegPrimary}, &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(-1)}); err != ErrInvalidVolume {
+		t.Errorf("negative volume should be rejected, got %v", err)
+	}
+	if b.BLength() != 0 || b.GetOrder(1) != nil {
+		t.Errorf("rejected orders should not rest")
+	}
+}
+
+func TestOrderbookOutOfRange(t *testing.T) {
+	b := NewOrderbook()
+	if err := b.Add(decimal.NewFromInt(100000000000), &Order{Id: 1, Volume: decimal.NewFromInt(1)}); err != ErrInvalidPrice {
+		t.Errorf("price out of the int64 range should be rejected, got %v", err)
+	}
+	if err := b.Add(decimal.NewFromInt(10), &Order{Id: 2, Volume: decimal.NewFromInt(200000000000)}); err != ErrInvalidVolume {
+		t.Errorf("volume out of the int64 range should be rejected, got %v", err)
+	}
+	if err := b.Amend(2, decimal.NewFromInt(10), decimal.NewFromInt(200000000000)); err != ErrInvalidVolume {
+		t.Errorf("amended volume out of the int64 range should be rejected, got %v", err)
+	}
+	if b.ALength() != 0 {
+		t.Errorf("rejected orders should not rest")
+	}
+
+	if DefaultInstrument.Lots(decimal.NewFromInt(200000000000)) != math.MaxInt64 {
+		t.Errorf("lots should be clamped to the int64 range")
+	}
+}
+
+func TestInstrumentInvalid(t *testing.T) {
+	if (Instrument{}).Validate() != ErrInvalidInstrument {
+		t.Errorf("zero sizes should be invalid")
+	}
+	if (Instrument{TickSize: decimal.NewFromInt(1), LotSize: decimal.NewFromInt(-1)}).Validate() != ErrInvalidInstrument {
+		t.Errorf("negative lot size should be invalid")
+	}
+
+	defer func() {
+		if r := recover(); r != ErrInvalidInstrument {
+			t.Errorf("a book with an invalid instrument should not be created, got %v", r)
+		}
+	}()
+	NewOrderbookWithInstrument(Instrument{})
+}
+
+func TestOrderbookTicks(t *testing.T) {
+	b := NewOrderbookWithInstrument(NewInstrument(decimal.NewFromFloat(0.5), decimal.NewFromFloat(0.1)))
+	if err := b.AddTicks(21, 0, &Order{Id: 1}); err != ErrInvalidVolume {
+		t.Errorf("order without lots should be rejected, got %v", err)
+	}
+	if err := b.AddTicks(21, 15, &Order{Id: 1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !b.GetBestOffer().Equal(decimal.RequireFromString("10.5")) {
+		t.Errorf("expected best offer 10.5, got %v", b.GetBestOffer())
+	}
+
+	trades := make([]Trade, 0, 1)
+	trades, err := b.MatchTicks(21, 10, &Order{Id: 2, BidOrAsk: true}, trades)
+	if err != nil || len(trades) != 1 || trades[0].Lots != 10 {
+		t.Fatalf("expected a trade of 10 lots, got %v %v", trades, err)
+	}
+	if v := b.GetOrder(1).Remaining(); !v.Equal(decimal.RequireFromString("0.5")) {
+		t.Errorf("expected 0.5 remaining, got %v", v)
+	}
+}
+
+func BenchmarkPriceCompareDecimal(b *testing.B) {
+	prices := make([]decimal.Decimal, 1024)
+	for i := range prices {
+		prices[i] = decimal.NewFromFloat(rand.Float64())
+	}
+
+	b.ResetTimer()
+	n := 0
+	for i := 0; i < b.N; i += 1 {
+		if prices[i&1023].LessThan(prices[(i+1)&1023]) {
+			n++
+		}
+	}
+}
+
+func BenchmarkPriceCompareTicks(b *testing.B) {
+	prices := make([]int64, 1024)
+	for i := range prices {
+		prices[i] = DefaultInstrument.Ticks(decimal.NewFromFloat(rand.Float64()))
+	}
+
+	b.ResetTimer()
+	n := 0
+	for i := 0; i < b.N; i += 1 {
+		if prices[i&1023] < prices[(i+1)&1023] {
+			n++
+		}
+	}
+}
+
+func BenchmarkInstrumentTicks(b *testing.B) {
+	price := decimal.NewFromFloat(rand.Float64())
+	for i := 0; i < b.N; i += 1 {
+		DefaultInstrument.Ticks(price)
+	}
+}
@@ -0,0 +1,383 @@
+package rbt_orderbook
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// default number of ticks of a price ladder window
+const LadderWindow int = 4096
+
+// default limit of the number of ticks a price ladder window can grow to, 8MB of
+// levels per side
+const LadderMaxWindow int = 1 << 20
+
+// Book side of limits indexed directly by price in ticks within a window of ticks.
+// A bitmap of used levels finds the next limit 64 ticks at a time. A key out of the
+// window recenters the window around the keys, the window is doubled if the keys
+// span more than half of it. Keys which do not fit the largest window spill into a
+// red-black tree. Values must not be nil.
+type priceLadder struct {
+	levels    []*LimitOrder // limits by key - base
+	used      []uint64      // bitmap of used levels
+	base      int64         // key of the first level
+	size      int           // number of keys in the window
+	min       int64         // min and max keys in the window, valid if size > 0
+	max       int64
+	maxWindow int
+	over      *redBlackBST // keys out of the window, nil until a key spills
+}
+
+func newPriceLadder(window, maxWindow int) priceLadder {
+	// whole words of the bitmap
+	window = max(64, (window+63)&^63)
+	maxWindow = max(window, (maxWindow+63)&^63)
+	return priceLadder{
+		levels:    make([]*LimitOrder, window),
+		used:      make([]uint64, window/64),
+		maxWindow: maxWindow,
+	}
+}
+
+// reports if there are keys out of the window
+func (this *priceLadder) spilled() bool {
+	return this.over != nil && !this.over.IsEmpty()
+}
+
+func (this *priceLadder) Size() int {
+	if this.over != nil {
+		return this.size + this.over.Size()
+	}
+	return this.size
+}
+
+func (this *priceLadder) IsEmpty() bool {
+	return this.Size() == 0
+}
+
+func (this *priceLadder) panicIfEmpty() {
+	if this.IsEmpty() {
+		panic("price ladder is empty")
+	}
+}
+
+// returns the level index of the key if it is in the window
+func (this *priceLadder) index(key int64) (int, bool) {
+	i := key - this.base
+	return int(i), i >= 0 && i < int64(len(this.levels))
+}
+
+func (this *priceLadder) isUsed(i int) bool {
+	return this.used[i>>6]&(1<<(i&63)) != 0
+}
+
+func (this *priceLadder) Contains(key int64) bool {
+	i, ok := this.index(key)
+	if !ok {
+		return this.spilled() && this.over.Contains(key)
+	}
+	return this.isUsed(i)
+}
+
+func (this *priceLadder) Get(key int64) *LimitOrder {
+	this.panicIfEmpty()
+
+	i, ok := this.index(key)
+	if !ok && this.spilled() {
+		return this.over.Get(key)
+	}
+	if !ok || !this.isUsed(i) {
+		panic(fmt.Sprintf("key %d does not exist", key))
+	}
+	return this.levels[i]
+}
+
+func (this *priceLadder) Put(key int64, value *LimitOrder) {
+	i, ok := this.index(key)
+	if !ok && !this.recenter(key) {
+		if this.over == nil {
+			t := NewRedBlackBST()
+			this.over = &t
+		}
+		this.over.Put(key, value)
+		return
+	}
+	if !ok {
+		i, _ = this.index(key)
+	}
+	this.put(i, value)
+}
+
+// puts the value at the level index in the window
+func (this *priceLadder) put(i int, value *LimitOrder) {
+	key := this.base + int64(i)
+	if !this.isUsed(i) {
+		this.used[i>>6] |= 1 << (i & 63)
+		this.size++
+		if this.size == 1 || key < this.min {
+			this.min = key
+		}
+		if this.size == 1 || key > this.max {
+			this.max = key
+		}
+	}
+	this.levels[i] = value
+}
+
+func (this *priceLadder) Delete(key int64) error {
+	if !this.Contains(key) {
+		return ErrUnknownLevel
+	}
+
+	i, ok := this.index(key)
+	if !ok {
+		return this.over.Delete(key)
+	}
+	this.used[i>>6] &^= 1 << (i & 63)
+	this.levels[i] = nil
+	this.size--
+
+	// moving the best price cursors
+	if this.size > 0 && key == this.min {
+		this.min = this.base + int64(this.next(i))
+	}
+	if this.size > 0 && key == this.max {
+		this.max = this.base + int64(this.prev(i))
+	}
+	return nil
+}
+
+func (this *priceLadder) Min() int64 {
+	this.panicIfEmpty()
+	if this.spilled() && (this.size == 0 || this.over.Min() < this.min) {
+		return this.over.Min()
+	}
+	return this.min
+}
+
+func (this *priceLadder) Max() int64 {
+	this.panicIfEmpty()
+	if this.spilled() && (this.size == 0 || this.over.Max() > this.max) {
+		return this.over.Max()
+	}
+	return this.max
+}
+
+func (this *priceLadder) MinValue() *LimitOrder {
+	if this.spilled() {
+		return this.Get(this.Min())
+	}
+	this.panicIfEmpty()
+	return this.levels[this.min-this.base]
+}
+
+func (this *priceLadder) MaxValue() *LimitOrder {
+	if this.spilled() {
+		return this.Get(this.Max())
+	}
+	this.panicIfEmpty()
+	return this.levels[this.max-this.base]
+}
+
+func (this *priceLadder) Floor(key int64) int64 {
+	this.panicIfEmpty()
+
+	k, ok := this.floor(key)
+	if !ok {
+		panic(fmt.Sprintf("there are no keys <= %d", key))
+	}
+	return k
+}
+
+func (this *priceLadder) Ceiling(key int64) int64 {
+	this.panicIfEmpty()
+
+	k, ok := this.ceiling(key)
+	if !ok {
+		panic(fmt.Sprintf("there are no keys >= %d", key))
+	}
+	return k
+}
+
+// returns the largest key <= key of the window and the spilled keys
+func (this *priceLadder) floor(key int64) (int64, bool) {
+	k, ok := int64(0), false
+	if this.size > 0 && key >= this.min {
+		k, ok = this.max, true
+		if key < this.max {
+			k = this.base + int64(this.prev(int(key-this.base)))
+		}
+	}
+	if this.spilled() && key >= this.over.Min() {
+		if o := this.over.Floor(key); !ok || o > k {
+			k, ok = o, true
+		}
+	}
+	return k, ok
+}
+
+// returns the smallest key >= key of the window and the spilled keys
+func (this *priceLadder) ceiling(key int64) (int64, bool) {
+	k, ok := int64(0), false
+	if this.size > 0 && key <= this.max {
+		k, ok = this.min, true
+		if key > this.min {
+			k = this.base + int64(this.next(int(key-this.base)))
+		}
+	}
+	if this.spilled() && key <= this.over.Max() {
+		if o := this.over.Ceiling(key); !ok || o < k {
+			k, ok = o, true
+		}
+	}
+	return k, ok
+}
+
+func (this *priceLadder) Higher(key int64) *LimitOrder {
+	if this.spilled() {
+		if k, ok := this.ceiling(key + 1); ok {
+			return this.Get(k)
+		}
+		return nil
+	}
+
+	if this.size == 0 || key >= this.max {
+		return nil
+	}
+	if key < this.min {
+		return this.levels[this.min-this.base]
+	}
+	return this.levels[this.next(int(key-this.base)+1)]
+}
+
+func (this *priceLadder) Lower(key int64) *LimitOrder {
+	if this.spilled() {
+		if k, ok := this.floor(key - 1); ok {
+			return this.Get(k)
+		}
+		return nil
+	}
+
+	if this.size == 0 || key <= this.min {
+		return nil
+	}
+	if key > this.max {
+		return this.levels[this.max-this.base]
+	}
+	return this.levels[this.prev(int(key-this.base)-1)]
+}
+
+// returns the first used level index >= i or -1
+func (this *priceLadder) next(i int) int {
+	if i >= len(this.levels) {
+		return -1
+	}
+
+	w := i >> 6
+	if word := this.used[w] >> (i & 63); word != 0 {
+		return i + bits.TrailingZeros64(word)
+	}
+	for w++; w < len(this.used); w += 1 {
+		if this.used[w] != 0 {
+			return w<<6 + bits.TrailingZeros64(this.used[w])
+		}
+	}
+	return -1
+}
+
+// returns the last used level index <= i or -1
+func (this *priceLadder) prev(i int) int {
+	if i < 0 {
+		return -1
+	}
+
+	w := i >> 6
+	if word := this.used[w] << (63 - (i & 63)); word != 0 {
+		return i - bits.LeadingZeros64(word)
+	}
+	for w--; w >= 0; w -= 1 {
+		if this.used[w] != 0 {
+			return w<<6 + 63 - bits.LeadingZeros64(this.used[w])
+		}
+	}
+	return -1
+}
+
+// moves the window so that the keys of the window and the new key are in the
+// middle of it, reports false if they do not fit the largest window
+func (this *priceLadder) recenter(key int64) bool {
+	lo, hi := key, key
+	if this.size > 0 {
+		lo, hi = min(lo, this.min), max(hi, this.max)
+	}
+
+	span := hi - lo + 1
+	window := len(this.levels)
+	for 2*span > int64(window) && window < this.maxWindow {
+		window = min(2*window, this.maxWindow)
+	}
+	if span > int64(window) {
+		return false
+	}
+	base := lo - (int64(window)-span)/2
+
+	if window != len(this.levels) {
+		this.grow(window, base)
+	} else {
+		this.move(base)
+	}
+	this.unspill()
+	return true
+}
+
+// moves the window of the same size to the base
+func (this *priceLadder) move(base int64) {
+	window := len(this.levels)
+
+	// the windows overlap as both contain the keys
+	if shift := int(base - this.base); this.size > 0 && shift > 0 {
+		copy(this.levels, this.levels[shift:])
+		clear(this.levels[window-shift:])
+	} else if this.size > 0 && shift < 0 {
+		copy(this.levels[-shift:], this.levels)
+		clear(this.levels[:-shift])
+	}
+	this.base = base
+
+	clear(this.used)
+	for i, limit := range this.levels {
+		if limit != nil {
+			this.used[i>>6] |= 1 << (i & 63)
+		}
+	}
+}
+
+// moves the spilled keys the window covers now into the window
+func (this *priceLadder) unspill() {
+	end := this.base + int64(len(this.levels)) - 1
+	for this.spilled() && this.over.Max() >= this.base {
+		key := this.over.Ceiling(this.base)
+		if key > end {
+			return
+		}
+
+		i, _ := this.index(key)
+		this.put(i, this.over.Get(key))
+		this.over.Delete(key)
+	}
+}
+
+// moves the levels to a larger window
+func (this *priceLadder) grow(window int, base int64) {
+	levels := make([]*LimitOrder, window)
+	used := make([]uint64, window/64)
+	for i := this.next(0); i >= 0; i = this.next(i + 1) {
+		j := int(this.base + int64(i) - base)
+		levels[j] = this.levels[i]
+		used[j>>6] |= 1 << (j & 63)
+	}
+
+	this.levels = levels
+	this.used = used
+	this.base = base
+}
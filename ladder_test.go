@@ -0,0 +1,267 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+	"math/rand"
+	"testing"
+)
+
+func TestPriceLadderEmpty(t *testing.T) {
+	l := newPriceLadder(LadderWindow, LadderMaxWindow)
+	if !l.IsEmpty() || l.Size() != 0 || l.Contains(1) || l.Higher(0) != nil || l.Lower(0) != nil {
+		t.Errorf("a ladder should be initialized as empty")
+	}
+	if l.Delete(1) != ErrUnknownLevel {
+		t.Errorf("deleting a missing key should fail")
+	}
+}
+
+func TestPriceLadderRecenter(t *testing.T) {
+	l := newPriceLadder(64, LadderMaxWindow)
+	for _, key := range []int64{1000, 1010, 1060, 990} {
+		l.Put(key, &LimitOrder{ticks: key})
+	}
+	if l.Min() != 990 || l.Max() != 1060 || l.Size() != 4 {
+		t.Errorf("invalid keys after recentering: %d..%d of %d", l.Min(), l.Max(), l.Size())
+	}
+	if len(l.levels) != 128 {
+		t.Errorf("window should be doubled to fit the keys, got %d", len(l.levels))
+	}
+	for _, key := range []int64{1000, 1010, 1060, 990} {
+		if !l.Contains(key) || l.Get(key).ticks != key {
+			t.Errorf("key %d should be found", key)
+		}
+	}
+
+	// drifting down keeps the window size
+	l.Delete(1060)
+	l.Delete(1010)
+	l.Put(950, &LimitOrder{ticks: 950})
+	if len(l.levels) != 128 || !l.Contains(1000) || !l.Contains(950) || l.Contains(1060) || l.Min() != 950 {
+		t.Errorf("window should be moved keeping the keys")
+	}
+}
+
+func TestPriceLadderMaxWindow(t *testing.T) {
+	l := newPriceLadder(64, 256)
+	far := int64(1e10)
+	for _, key := range []int64{1000, 1100, far, 900} {
+		l.Put(key, &LimitOrder{ticks: key})
+	}
+	if len(l.levels) != 256 || l.over.Size() != 1 {
+		t.Errorf("window should be capped with one key spilled, got %d levels", len(l.levels))
+	}
+	if l.Size() != 4 || l.Min() != 900 || l.Max() != far || l.Higher(1100).ticks != far || l.Lower(far).ticks != 1100 {
+		t.Errorf("spilled key should be found in order")
+	}
+	if l.Floor(far-1) != 1100 || l.Ceiling(1101) != far || l.MaxValue().ticks != far {
+		t.Errorf("invalid floor or ceiling of the spilled key")
+	}
+
+	// the window follows the keys once the old ones are gone
+	l.Delete(900)
+	l.Delete(1000)
+	l.Delete(1100)
+	l.Put(far+100, &LimitOrder{ticks: far + 100})
+	if l.over.Size() != 0 || !l.Contains(far) || l.Get(far).ticks != far || l.Min() != far {
+		t.Errorf("spilled key should be moved into the window")
+	}
+}
+
+// prices of the default instrument are 1e8 ticks apart per unit
+func TestPriceLadderOrderbookMaxWindow(t *testing.T) {
+	b := NewOrderbook(WithPriceLadder(LadderWindow, LadderMaxWindow))
+	b.Add(decimal.NewFromInt(100), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(101), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+
+	if l := b.Bids.(*priceLadder); len(l.levels) > LadderMaxWindow {
+		t.Errorf("window should not grow over %d, got %d", LadderMaxWindow, len(l.levels))
+	}
+	if !b.GetBestBid().Equal(decimal.NewFromInt(101)) || b.BLength() != 2 {
+		t.Errorf("both limits should be in the book")
+	}
+
+	trades, _ := b.Match(decimal.NewFromInt(100), &Order{Id: 3, Volume: decimal.NewFromInt(2)})
+	if len(trades) != 2 || b.BLength() != 0 {
+		t.Errorf("both limits should be matched, got %d trades", len(trades))
+	}
+}
+
+// compares the ladder with the tree after random operations with drifting keys
+func TestPriceLadderRandom(t *testing.T) {
+	testPriceLadderRandom(t, newPriceLadder(64, LadderMaxWindow), 50)
+}
+
+// keys spread over more than the largest window spill into the tree
+func TestPriceLadderRandomSpilled(t *testing.T) {
+	testPriceLadderRandom(t, newPriceLadder(64, 128), 200)
+}
+
+func testPriceLadderRandom(t *testing.T, l priceLadder, spread int) {
+	r := rand.New(rand.NewSource(1))
+	tr := NewRedBlackBST()
+	var keys []int64
+	center := int64(10000)
+
+	for i := 0; i < 20000; i += 1 {
+		center += int64(r.Intn(5) - 2)
+		key := center + int64(r.Intn(2*spread)-spread)
+
+		if r.Intn(2) == 0 || len(keys) == 0 {
+			if !tr.Contains(key) {
+				keys = append(keys, key)
+			}
+			value := &LimitOrder{ticks: key}
+			l.Put(key, value)
+			tr.Put(key, value)
+		} else {
+			k := r.Intn(len(keys))
+			key = keys[k]
+			keys = append(keys[:k], keys[k+1:]...)
+			if err := l.Delete(key); err != nil {
+				t.Fatalf("key %d should be deleted: %v", key, err)
+			}
+			tr.Delete(key)
+		}
+
+		if l.Size() != tr.Size() {
+			t.Fatalf("ladder size %d, expected %d", l.Size(), tr.Size())
+		}
+		if tr.IsEmpty() {
+			continue
+		}
+		if l.Min() != tr.Min() || l.Max() != tr.Max() {
+			t.Fatalf("ladder keys %d..%d, expected %d..%d", l.Min(), l.Max(), tr.Min(), tr.Max())
+		}
+		if l.Contains(key) != tr.Contains(key) || tr.Contains(key) && l.Get(key) != tr.Get(key) {
+			t.Fatalf("invalid value of key %d", key)
+		}
+		if key >= tr.Min() && l.Floor(key) != tr.Floor(key) {
+			t.Fatalf("floor of %d is %d, expected %d", key, l.Floor(key), tr.Floor(key))
+		}
+		if key <= tr.Max() && l.Ceiling(key) != tr.Ceiling(key) {
+			t.Fatalf("ceiling of %d is %d, expected %d", key, l.Ceiling(key), tr.Ceiling(key))
+		}
+		if l.Higher(key) != tr.Higher(key) || l.Lower(key) != tr.Lower(key) {
+			t.Fatalf("invalid neighbours of %d", key)
+		}
+	}
+
+	n := 0
+	for limit := l.MinValue(); limit != nil; limit = l.Higher(limit.ticks) {
+		n++
+	}
+	if n != tr.Size() {
+		t.Errorf("walked %d keys, expected %d", n, tr.Size())
+	}
+}
+
+// books with tree and ladder sides should trade the same
+func TestPriceLadderOrderbook(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	inst := WithInstrument(NewInstrument(decimal.NewFromInt(1), decimal.NewFromInt(1)))
+	tree := NewOrderbook(inst)
+	ladder := NewOrderbook(inst, WithPriceLadder(64, LadderMaxWindow))
+
+	for i := 1; i <= 5000; i += 1 {
+		price := decimal.NewFromInt(int64(900 + r.Intn(200)))
+		volume := decimal.NewFromInt(int64(1 + r.Intn(5)))
+		bidOrAsk := r.Intn(2) == 0
+
+		if r.Intn(4) == 0 {
+			id := 1 + r.Intn(i)
+			if (tree.CancelById(id) == nil) != (ladder.CancelById(id) == nil) {
+				t.Fatalf("cancel of order %d differs", id)
+			}
+			continue
+		}
+
+		trades1, _ := tree.Match(price, &Order{Id: i, BidOrAsk: bidOrAsk, Volume: volume})
+		trades2, _ := ladder.Match(price, &Order{Id: i, BidOrAsk: bidOrAsk, Volume: volume})
+		if len(trades1) != len(trades2) {
+			t.Fatalf("order %d traded %d times, expected %d", i, len(trades2), len(trades1))
+		}
+		for k := range trades1 {
+			if trades1[k].MakerId != trades2[k].MakerId || trades1[k].Lots != trades2[k].Lots || !trades1[k].Price.Equal(trades2[k].Price) {
+				t.Fatalf("trade %d of order %d differs: %+v, expected %+v", k, i, trades2[k], trades1[k])
+			}
+		}
+	}
+
+	bids1, asks1 := tree.Depth(1000)
+	bids2, asks2 := ladder.Depth(1000)
+	if len(bids1) != len(bids2) || len(asks1) != len(asks2) {
+		t.Fatalf("depth differs")
+	}
+	for k := range bids1 {
+		if !bids1[k].Price.Equal(bids2[k].Price) || bids1[k].Lots != bids2[k].Lots {
+			t.Errorf("bid level %d differs", k)
+		}
+	}
+	for k := range asks1 {
+		if !asks1[k].Price.Equal(asks2[k].Price) || asks1[k].Lots != asks2[k].Lots {
+			t.Errorf("ask level %d differs", k)
+		}
+	}
+}
+
+// random limits around a drifting price, the best limit is looked up after every change
+func benchmarkSide(side bookSide, b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	values := make([]LimitOrder, 1000)
+	keys := make([]int64, 0, len(values))
+	center := int64(1e6)
+
+	ops := make([]int64, 4096)
+	for i := range ops {
+		center += int64(r.Intn(3) - 1)
+		ops[i] = center + int64(r.Intn(500))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 1 {
+		key := ops[i%len(ops)]
+		if len(keys) < cap(keys) && !side.Contains(key) {
+			side.Put(key, &values[len(keys)])
+			keys = append(keys, key)
+		} else if len(keys) > 0 {
+			k := i % len(keys)
+			side.Delete(keys[k])
+			keys[k] = keys[len(keys)-1]
+			keys = keys[:len(keys)-1]
+		}
+
+		if !side.IsEmpty() {
+			side.Higher(side.Min())
+		}
+	}
+}
+
+func BenchmarkTreeSide(b *testing.B) {
+	benchmarkSide(newTreeSide(), b)
+}
+
+func BenchmarkLadderSide(b *testing.B) {
+	l := newPriceLadder(LadderWindow, LadderMaxWindow)
+	benchmarkSide(&l, b)
+}
+
+func BenchmarkOrderbookSteadyAddCancelLadder(b *testing.B) {
+	s := newSteadyBook(WithPriceLadder(LadderWindow, LadderMaxWindow))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 1 {
+		s.add()
+		s.cancel()
+	}
+}
+
+func BenchmarkOrderbookSteadyAddMatchLadder(b *testing.B) {
+	s := newSteadyBook(WithPriceLadder(LadderWindow, LadderMaxWindow))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 1 {
+		s.match(s.add().BidOrAsk)
+	}
+}
@@ -8,21 +8,36 @@ import (
 type LimitOrder struct {
 	Price decimal.Decimal
 
-	orders      *ordersQueue
-	totalVolume decimal.Decimal
+	ticks        int64
+	orders       OrderQueue
+	totalVolume  int64 // displayed volume in lots
+	hiddenVolume int64 // iceberg reserve volume in lots
+	pegged       int   // number of pegged orders
+	aon          int   // number of all-or-none orders
+	inst         *Instrument
 }
 
 func NewLimitOrder(price decimal.Decimal) LimitOrder {
-	q := NewOrdersQueue()
+	return newLimitOrder(&DefaultInstrument, DefaultInstrument.Ticks(price))
+}
+
+func newLimitOrder(inst *Instrument, ticks int64) LimitOrder {
 	return LimitOrder{
-		Price:       price,
-		orders:      &q,
-		totalVolume: decimal.NewFromFloat(0.0),
+		Price:  inst.Price(ticks),
+		ticks:  ticks,
+		orders: LinkedOrderQueue(),
+		inst:   inst,
 	}
 }
 
+// returns the displayed volume at the limit
 func (this *LimitOrder) TotalVolume() decimal.Decimal {
-	return this.totalVolume
+	return this.inst.Volume(this.totalVolume)
+}
+
+// returns the iceberg reserve volume at the limit
+func (this *LimitOrder) HiddenVolume() decimal.Decimal {
+	return this.inst.Volume(this.hiddenVolume)
 }
 
 func (this *LimitOrder) Size() int {
@@ -30,9 +45,22 @@ func (this *LimitOrder) Size() int {
 }
 
 func (this *LimitOrder) Enqueue(o *Order) {
+	if o.inst == nil {
+		// the order has not entered a book yet
+		o.admit(this.inst)
+	}
+
+	o.hide()
 	this.orders.Enqueue(o)
 	o.Limit = this
-	this.totalVolume = this.totalVolume.Add(o.Volume)
+	this.totalVolume += o.lots
+	this.hiddenVolume += o.hidden
+	if o.pegged {
+		this.pegged++
+	}
+	if o.AON {
+		this.aon++
+	}
 }
 
 func (this *LimitOrder) Dequeue() *Order {
@@ -41,22 +69,87 @@ func (this *LimitOrder) Dequeue() *Order {
 	}
 
 	o := this.orders.Dequeue()
-	this.totalVolume = this.totalVolume.Sub(o.Volume)
+	o.Limit = nil
+	this.totalVolume -= o.lots
+	this.hiddenVolume -= o.hidden
+	if o.pegged {
+		this.pegged--
+	}
+	if o.AON {
+		this.aon--
+	}
 	return o
 }
 
-func (this *LimitOrder) Delete(o *Order) {
+// calls f for every order at the limit in FIFO order
+func (this *LimitOrder) Each(f func(o *Order)) {
+	this.orders.Each(f)
+}
+
+// returns the oldest order at the limit without removing it
+func (this *LimitOrder) Peek() *Order {
+	return this.orders.Head()
+}
+
+// returns the order following o in the queue or nil
+func (this *LimitOrder) Next(o *Order) *Order {
+	return this.orders.Next(o)
+}
+
+// reduces order volume by matched lots, removing the order once it is fully filled.
+// An iceberg order is replenished from its reserve and moved to the back of the queue.
+func (this *LimitOrder) Fill(o *Order, lots int64) {
+	o.lots -= lots
+	this.totalVolume -= lots
+
+	if o.lots <= 0 {
+		this.Delete(o)
+
+		if o.hidden > 0 {
+			o.replenish()
+			this.Enqueue(o)
+		}
+	}
+}
+
+// decreases order open volume to a new number of lots in place keeping its position
+// in the queue, the iceberg reserve is reduced first
+func (this *LimitOrder) Reduce(o *Order, lots int64) {
+	hidden := max(lots-o.lots, 0)
+	this.hiddenVolume -= o.hidden - hidden
+	o.hidden = hidden
+
+	shown := lots - hidden
+	this.totalVolume -= o.lots - shown
+	o.lots = shown
+}
+
+func (this *LimitOrder) Delete(o *Order) error {
 	if o.Limit != this {
-		panic("order does not belong to the limit")
+		return ErrUnknownOrder
 	}
 
 	this.orders.Delete(o)
 	o.Limit = nil
-	this.totalVolume = this.totalVolume.Sub(o.Volume)
+	this.totalVolume -= o.lots
+	this.hiddenVolume -= o.hidden
+	if o.pegged {
+		this.pegged--
+	}
+	if o.AON {
+		this.aon--
+	}
+	return nil
 }
 
+// removes all orders from the limit
 func (this *LimitOrder) Clear() {
-	q := NewOrdersQueue()
-	this.orders = &q
-	this.totalVolume = decimal.Zero
+	this.orders.Each(func(o *Order) {
+		o.Limit = nil
+	})
+	this.orders.Clear()
+	this.totalVolume = 0
+	this.hiddenVolume = 0
+	this.pegged = 0
+	this.aon = 0
 }
@@ -41,8 +41,8 @@ func TestLimitOrderAddMultipleOrders(t *testing.T) {
 	n := 100
 	for i := 0; i < n; i += 1 {
 		o := &Order{Id: i, Volume: decimal.NewFromFloat(rand.Float64())}
-		volume = volume.Add(o.Volume)
 		l.Enqueue(o)
+		volume = volume.Add(o.Remaining())
 	}
 	if volume.Cmp(l.TotalVolume()) != 0 {
 		t.Errorf("total volume calculated incorrectly")
@@ -53,7 +53,7 @@ func TestLimitOrderAddMultipleOrders(t *testing.T) {
 	}
 
 	o := l.Dequeue()
-	if l.TotalVolume().Cmp(volume.Sub(o.Volume)) != 0 {
+	if l.TotalVolume().Cmp(volume.Sub(o.Remaining())) != 0 {
 		t.Errorf("total volume calculated incorrectly")
 	}
 	if l.Size() != n-1 {
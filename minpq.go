@@ -1,16 +1,14 @@
 package rbt_orderbook
 
-import "github.com/shopspring/decimal"
-
 // Mininum oriented Priority Queue
 type minPQ struct {
-	keys []decimal.Decimal
+	keys []int64
 	n    int
 }
 
 func NewMinPQ(size int) minPQ {
 	return minPQ{
-		keys: make([]decimal.Decimal, size+1),
+		keys: make([]int64, size+1),
 	}
 }
 
@@ -22,9 +20,9 @@ func (pq *minPQ) IsEmpty() bool {
 	return pq.n == 0
 }
 
-func (pq *minPQ) Insert(key decimal.Decimal) {
+func (pq *minPQ) Insert(key int64) error {
 	if pq.n+1 == cap(pq.keys) {
-		panic("pq is full")
+		return ErrQueueFull
 	}
 
 	pq.n++
@@ -32,9 +30,17 @@ func (pq *minPQ) Insert(key decimal.Decimal) {
 
 	// restore order: LogN
 	pq.swim(pq.n)
+	return nil
+}
+
+// reallocates the queue to hold up to size keys
+func (pq *minPQ) grow(size int) {
+	keys := make([]int64, size+1)
+	copy(keys, pq.keys[:pq.n+1])
+	pq.keys = keys
 }
 
-func (pq *minPQ) Top() decimal.Decimal {
+func (pq *minPQ) Top() int64 {
 	if pq.IsEmpty() {
 		panic("pq is empty")
 	}
@@ -43,7 +49,7 @@ func (pq *minPQ) Top() decimal.Decimal {
 }
 
 // removes minimal element and returns it
-func (pq *minPQ) DelTop() decimal.Decimal {
+func (pq *minPQ) DelTop() int64 {
 	if pq.IsEmpty() {
 		panic("pq is empty")
 	}
@@ -59,7 +65,7 @@ func (pq *minPQ) DelTop() decimal.Decimal {
 }
 
 func (pq *minPQ) swim(k int) {
-	for k > 1 && pq.keys[k].LessThan(pq.keys[k/2]) {
+	for k > 1 && pq.keys[k] < pq.keys[k/2] {
 		// swap
 		pq.keys[k], pq.keys[k/2] = pq.keys[k/2], pq.keys[k]
 		k = k / 2
@@ -70,11 +76,11 @@ func (pq *minPQ) sink(k int) {
 	for 2*k <= pq.n {
 		c := 2 * k
 		// select minimum of two children
-		if c < pq.n && pq.keys[c+1].LessThan(pq.keys[c]) {
+		if c < pq.n && pq.keys[c+1] < pq.keys[c] {
 			c++
 		}
 
-		if pq.keys[c].LessThan(pq.keys[k]) {
+		if pq.keys[c] < pq.keys[k] {
 			// swap
 			pq.keys[c], pq.keys[k] = pq.keys[k], pq.keys[c]
 			k = c
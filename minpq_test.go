@@ -9,46 +9,36 @@ import (
 
 func TestMinPQOne(t *testing.T) {
 	minpq := NewMinPQ(10)
-	minpq.Insert(decimal.NewFromFloat(5.0))
+	minpq.Insert(5)
 	res := minpq.Top()
 
-	if !res.Equal(decimal.NewFromInt(5.0)) {
+	if res != 5 {
 		t.Errorf("actual %+v != expected %+v", res, 5)
 	}
 }
 
 func TestMinPQTwo(t *testing.T) {
 	minpq := NewMinPQ(10)
-	minpq.Insert(decimal.NewFromFloat(6.0))
-	minpq.Insert(decimal.NewFromFloat(5.0))
-	res := [2]decimal.Decimal{}
+	minpq.Insert(6)
+	minpq.Insert(5)
+	res := [2]int64{}
 	res[0] = minpq.Top()
 	minpq.DelTop()
 	res[1] = minpq.Top()
 
-	exp := [2]decimal.Decimal{decimal.NewFromFloat(5.0), decimal.NewFromFloat(6.0)}
-	resMap := make(map[decimal.Decimal]decimal.Decimal)
-	expMap := make(map[decimal.Decimal]decimal.Decimal)
-	for _, v := range res {
-		resMap[v] = v
-	}
-	for _, v := range exp {
-		expMap[v] = v
-	}
-	for k, v := range resMap {
-		if v.Equal(expMap[k]) {
-			t.Errorf("actual resMap:%+v != expected expMap:%+v", resMap, expMap)
-		}
+	exp := [2]int64{5, 6}
+	if res != exp {
+		t.Errorf("actual %+v != expected %+v", res, exp)
 	}
 }
 
 func TestMinPQThree(t *testing.T) {
 	minpq := NewMinPQ(10)
-	minpq.Insert(decimal.NewFromFloat(6.0))
-	minpq.Insert(decimal.NewFromFloat(5.0))
-	minpq.Insert(decimal.NewFromFloat(4.0))
+	minpq.Insert(6)
+	minpq.Insert(5)
+	minpq.Insert(4)
 
-	res := [3]decimal.Decimal{}
+	res := [3]int64{}
 	res[0] = minpq.Top()
 	minpq.DelTop()
 	res[1] = minpq.Top()
@@ -56,23 +46,13 @@ func TestMinPQThree(t *testing.T) {
 	res[2] = minpq.Top()
 	minpq.DelTop()
 
-	exp := [3]decimal.Decimal{
-		decimal.NewFromFloat(4.0),
-		decimal.NewFromFloat(5.0),
-		decimal.NewFromFloat(6.0),
+	exp := [3]int64{
+		4,
+		5,
+		6,
 	}
-	resMap := make(map[decimal.Decimal]decimal.Decimal)
-	expMap := make(map[decimal.Decimal]decimal.Decimal)
-	for _, v := range res {
-		resMap[v] = v
-	}
-	for _, v := range exp {
-		expMap[v] = v
-	}
-	for k, v := range resMap {
-		if v.Equal(expMap[k]) {
-			t.Errorf("actual resMap:%+v != expected expMap:%+v", resMap, expMap)
-		}
+	if res != exp {
+		t.Errorf("actual %+v != expected %+v", res, exp)
 	}
 
 	if !minpq.IsEmpty() {
@@ -80,16 +60,25 @@ func TestMinPQThree(t *testing.T) {
 	}
 }
 
+func TestMinPQFull(t *testing.T) {
+	minpq := NewMinPQ(2)
+	minpq.Insert(1)
+	minpq.Insert(2)
+	if err := minpq.Insert(3); err != ErrQueueFull {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+}
+
 func TestMinPQRandom(t *testing.T) {
 	minpq := NewMinPQ(100)
 	for i := 0; i < 1000; i += 1 {
 		if minpq.Size() == 100 {
 			minpq.DelTop()
 		}
-		minpq.Insert(decimal.NewFromInt(int64(rand.Intn(100))))
+		minpq.Insert(int64(rand.Intn(100)))
 	}
 
-	res := make([]decimal.Decimal, 100)
+	res := make([]int64, 100)
 	for i := range res {
 		res[i] = minpq.Top()
 		minpq.DelTop()
@@ -100,7 +89,7 @@ func TestMinPQRandom(t *testing.T) {
 	}
 
 	for i := 1; i < 100; i += 1 {
-		if res[i].LessThan(res[i-1]) {
+		if res[i] < res[i-1] {
 			t.Errorf("invalid order")
 			break
 		}
@@ -111,9 +100,9 @@ func benchmarkMinPQLimitedRandomInsertWithCaching(n int, b *testing.B) {
 	pq := NewMinPQ(n)
 
 	// maximum number of levels in average is ~10k
-	limitslist := make([]decimal.Decimal, n)
+	limitslist := make([]int64, n)
 	for i := range limitslist {
-		limitslist[i] = decimal.NewFromFloat(rand.Float64())
+		limitslist[i] = rand.Int63n(1e8)
 	}
 
 	// preallocate empty orders
@@ -125,7 +114,7 @@ func benchmarkMinPQLimitedRandomInsertWithCaching(n int, b *testing.B) {
 	// measure insertion time
 	b.ResetTimer()
 
-	limitscache := make(map[decimal.Decimal]*LimitOrder)
+	limitscache := make(map[int64]*LimitOrder)
 	for i := 0; i < b.N; i += 1 {
 		// create a new order
 		o := orders[i]
@@ -145,7 +134,7 @@ func benchmarkMinPQLimitedRandomInsertWithCaching(n int, b *testing.B) {
 			limitscache[price].Enqueue(o)
 		} else {
 			// new limit
-			l := NewLimitOrder(price)
+			l := newLimitOrder(&DefaultInstrument, price)
 			l.Enqueue(o)
 
 			// caching limit
@@ -1,13 +1,111 @@
 package rbt_orderbook
 
-import "github.com/shopspring/decimal"
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Time in force of an incoming limit order
+type TimeInForce int
+
+const (
+	GTC TimeInForce = iota // good till cancel, the residual rests in the book
+	IOC                    // immediate or cancel, the residual is discarded
+	FOK                    // fill or kill, the order is either filled completely or discarded
+)
 
 // Single Order in an order book, as a node in a LimitOrder FIFO queue
 type Order struct {
-	Id       int
-	Volume   decimal.Decimal
-	Next     *Order
-	Prev     *Order
-	Limit    *LimitOrder
-	BidOrAsk bool
+	Id        int
+	Volume    decimal.Decimal // order volume on entry, see Remaining for the open volume
+	Next      *Order
+	Prev      *Order
+	Limit     *LimitOrder
+	BidOrAsk  bool
+	TIF       TimeInForce
+	PostOnly  bool            // the order must not take liquidity, see PostOnlyPolicy
+	Peak      decimal.Decimal // displayed volume of an iceberg order, zero displays the whole volume
+	Expiry    time.Time       // time the resting order is cancelled at, zero never expires
+	Owner     int             // account of the order for self-trade prevention, zero opts out
+	MinVolume decimal.Decimal // volume an incoming order must execute at once or it is cancelled
+	AON       bool            // all-or-none, the order trades its whole open volume at once only
+
+	lots    int64 // open displayed volume in lots
+	hidden  int64 // open reserve volume of an iceberg order in lots
+	peak    int64
+	minLots int64
+	inst    *Instrument
+
+	stop       int64 // trigger price of a stop order in ticks
+	limitPrice int64 // price of a stop-limit order in ticks
+	stopLimit  bool
+
+	trailing    bool
+	trail       Trail
+	trailOffset int64 // trail offset in ticks
+	slot        int   // index of a trailing stop in its queue
+
+	expiring   bool
+	expirySlot int // index of the order in the expiry queue
+
+	pegged    bool
+	peg       Peg
+	pegOffset int64 // peg offset in ticks
+	pegging   bool  // the order is in the list of pegged orders
+	pegSlot   int
+
+	group *orderGroup
+
+	slabSlot int // index of the order in a slab queue
+}
+
+// converts the order volume to lots of the instrument the order is entering
+func (o *Order) admit(inst *Instrument) {
+	o.admitLots(inst, inst.Lots(o.Volume))
+}
+
+// sets the open volume of the order entering the book, peak and minimum volume
+// are converted only if they are set
+func (o *Order) admitLots(inst *Instrument, lots int64) {
+	o.inst = inst
+	o.lots = lots
+	o.hidden = 0
+	o.peak = 0
+	if !o.Peak.IsZero() {
+		o.peak = inst.Lots(o.Peak)
+	}
+	o.minLots = 0
+	if !o.MinVolume.IsZero() {
+		o.minLots = inst.Lots(o.MinVolume)
+	}
+}
+
+// returns the volume in lots an incoming order has to execute at once
+func (o *Order) required() int64 {
+	if o.TIF == FOK || o.AON {
+		return o.lots
+	}
+	return min(o.minLots, o.lots)
+}
+
+// moves the volume above the peak of an iceberg order to the reserve
+func (o *Order) hide() {
+	if o.peak > 0 && o.lots > o.peak {
+		o.hidden += o.lots - o.peak
+		o.lots = o.peak
+	}
+}
+
+// refills the displayed volume of an iceberg order from the reserve
+func (o *Order) replenish() {
+	o.lots = min(o.peak, o.hidden)
+	o.hidden -= o.lots
+}
+
+// Remaining returns the open volume of the order including the iceberg reserve
+func (o *Order) Remaining() decimal.Decimal {
+	if o.inst == nil {
+		return o.Volume
+	}
+	return o.inst.Volume(o.lots + o.hidden)
 }
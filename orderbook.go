@@ -1,92 +1,354 @@
 package rbt_orderbook
 
 import (
-	"fmt"
 	"github.com/shopspring/decimal"
+	"math"
 	"sync"
+	"time"
 )
 
 // maximum limits per orderbook side to pre-allocate memory
 const MaxLimitsNum int = 10000
 
+// maximum number of cached decimal prices of limits
+const PriceCacheSize int = 2 * MaxLimitsNum
+
+// What happens to a post-only order which would cross the book
+type PostOnlyPolicy int
+
+const (
+	PostOnlyReject PostOnlyPolicy = iota // the order is rejected with ErrPostOnlyCross
+	PostOnlySlide                        // the order is re-priced one tick inside the spread
+)
+
+// What happens when an incoming order would trade with a resting order of the same owner
+type STPPolicy int
+
+const (
+	STPCancelNewest       STPPolicy = iota // the rest of the incoming order is cancelled
+	STPCancelOldest                        // the resting order is cancelled
+	STPCancelBoth                          // both orders are cancelled
+	STPDecrementAndCancel                  // both are decreased by the smaller volume, an empty one is cancelled
+)
+
 type Orderbook struct {
-	Bids           *redBlackBST
-	Asks           *redBlackBST
+	Bids           bookSide
+	Asks           bookSide
 	bidLimtRwLock  sync.RWMutex
-	bidLimitsCache map[decimal.Decimal]*LimitOrder
+	bidLimitsCache map[int64]*LimitOrder
 	askLimtRwLock  sync.RWMutex
-	askLimitsCache map[decimal.Decimal]*LimitOrder
+	askLimitsCache map[int64]*LimitOrder
 	pool           *sync.Pool
+	arena          orderArena
+	prices         map[int64]decimal.Decimal // decimal prices by ticks
+	emptied        []*LimitOrder             // limits emptied while walking a side
+	ordersRwLock   sync.RWMutex
+	orders         map[int]*Order
+	inst           *Instrument
+	postOnly       PostOnlyPolicy
+	stp            STPPolicy
+
+	stopsRwLock sync.RWMutex
+	stops       map[int]*Order
+	buyStops    stopSide
+	sellStops   stopSide
+	buyTrails   [2]trailSide // by reference
+	sellTrails  [2]trailSide
+	last        int64 // last trade price in ticks
+	traded      bool
+	expiries    orderPQ // resting orders by expiry time
+	clock       Clock
+	pegs        []*Order // pegged orders by slot
+	pegsFree    []int
+	pegRefs     pegRefs  // reference prices pegged orders are priced at
+	filled      []*Order // grouped orders filled since the groups were settled
+	left        []*Order // grouped orders which left the book or did not rest, detached once settled
+
+	seq            uint64
+	tradeHandlers  []TradeHandler
+	deltaHandlers  []DeltaHandler
+	rejectHandlers []RejectHandler
 }
 
-func NewOrderbook() Orderbook {
-	bids := NewRedBlackBST()
-	asks := NewRedBlackBST()
+// Callback receiving orders the book entered on its own, such as triggered stops,
+// and rejected with the error
+type RejectHandler func(o *Order, err error)
+
+// Option configures a book created by NewOrderbook
+type Option func(*options)
+
+type options struct {
+	instrument Instrument
+	newQueue   func() OrderQueue
+	newSide    func() bookSide
+}
+
+// WithInstrument sets price and volume granularity of the book, DefaultInstrument by default
+func WithInstrument(instrument Instrument) Option {
+	return func(opts *options) {
+		instrument.mustValidate()
+		opts.instrument = instrument
+	}
+}
+
+// WithOrderQueue sets the constructor of limit order queues, LinkedOrderQueue by default
+func WithOrderQueue(newQueue func() OrderQueue) Option {
+	return func(opts *options) {
+		opts.newQueue = newQueue
+	}
+}
+
+// WithPriceLadder keeps limits of each side in a price ladder of window ticks instead
+// of a red-black tree. A ladder is faster if prices stay within the window, which
+// recenters or grows up to maxWindow ticks when prices drift out of it, so the
+// instrument tick size should keep the price range of a side within a bounded
+// number of ticks. Prices out of the largest window are kept in a tree.
+func WithPriceLadder(window, maxWindow int) Option {
+	return func(opts *options) {
+		opts.newSide = func() bookSide {
+			l := newPriceLadder(window, maxWindow)
+			return &l
+		}
+	}
+}
+
+func NewOrderbook(opts ...Option) Orderbook {
+	o := options{
+		instrument: DefaultInstrument,
+		newQueue:   LinkedOrderQueue,
+		newSide:    newTreeSide,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	instrument := o.instrument
+	newQueue := o.newQueue
 	return Orderbook{
-		Bids: &bids,
-		Asks: &asks,
+		Bids: o.newSide(),
+		Asks: o.newSide(),
 
-		bidLimitsCache: make(map[decimal.Decimal]*LimitOrder, MaxLimitsNum),
-		askLimitsCache: make(map[decimal.Decimal]*LimitOrder, MaxLimitsNum),
+		bidLimitsCache: make(map[int64]*LimitOrder, MaxLimitsNum),
+		askLimitsCache: make(map[int64]*LimitOrder, MaxLimitsNum),
+		orders:         make(map[int]*Order),
+		prices:         make(map[int64]decimal.Decimal, PriceCacheSize),
+		inst:           &instrument,
+		stops:          make(map[int]*Order),
+		buyStops:       newStopSide(1),
+		sellStops:      newStopSide(-1),
+		buyTrails:      [2]trailSide{newTrailSide(1), newTrailSide(1)},
+		sellTrails:     [2]trailSide{newTrailSide(-1), newTrailSide(-1)},
+		expiries:       newOrderPQ(OrderPQSize),
+		clock:          time.Now,
 		pool: &sync.Pool{
 			New: func() interface{} {
-				limit := NewLimitOrder(decimal.NewFromFloat(0.0))
+				limit := newLimitOrder(&instrument, 0)
+				limit.orders = newQueue()
 				return &limit
 			},
 		},
 	}
 }
-func (this *Orderbook) getBidLimitsCacheByPrice(price decimal.Decimal) *LimitOrder {
-	var limit *LimitOrder
-	for k := range this.bidLimitsCache {
-		if k.Equal(price) {
-			limit = this.bidLimitsCache[k]
-		}
+
+func NewOrderbookWithInstrument(instrument Instrument) Orderbook {
+	return NewOrderbook(WithInstrument(instrument))
+}
+
+// Instrument returns price and volume granularity of the book
+func (this *Orderbook) Instrument() Instrument {
+	return *this.inst
+}
+
+// SetPostOnlyPolicy configures handling of crossing post-only orders
+func (this *Orderbook) SetPostOnlyPolicy(policy PostOnlyPolicy) {
+	this.postOnly = policy
+}
+
+// SetSTPPolicy configures self-trade prevention between orders of the same owner
+func (this *Orderbook) SetSTPPolicy(policy STPPolicy) {
+	this.stp = policy
+}
+
+// OnTrade registers a handler to be called for every trade in the book
+func (this *Orderbook) OnTrade(h TradeHandler) {
+	this.tradeHandlers = append(this.tradeHandlers, h)
+}
+
+// OnDelta registers a handler to be called for every limit update in the book
+func (this *Orderbook) OnDelta(h DeltaHandler) {
+	this.deltaHandlers = append(this.deltaHandlers, h)
+}
+
+// OnReject registers a handler to be called for every order rejected when it is
+// entered by the book itself rather than by a caller
+func (this *Orderbook) OnReject(h RejectHandler) {
+	this.rejectHandlers = append(this.rejectHandlers, h)
+}
+
+func (this *Orderbook) reject(o *Order, err error) {
+	for _, h := range this.rejectHandlers {
+		h(o, err)
 	}
-	return limit
 }
 
-func (this *Orderbook) getAskLimitsCacheByPrice(price decimal.Decimal) *LimitOrder {
-	var limit *LimitOrder
-	for k := range this.askLimitsCache {
-		if k.Equal(price) {
-			limit = this.askLimitsCache[k]
-		}
+// notifies delta handlers about the current state of the limit
+func (this *Orderbook) publish(limit *LimitOrder, bidOrAsk bool) {
+	if len(this.deltaHandlers) == 0 {
+		return
+	}
+
+	delta := Delta{
+		Seq:      this.nextSeq(),
+		BidOrAsk: bidOrAsk,
+		Price:    limit.Price,
+		Lots:     limit.totalVolume,
+		Orders:   limit.Size(),
+		inst:     this.inst,
+	}
+	for _, h := range this.deltaHandlers {
+		h(delta)
 	}
-	return limit
 }
 
-func (this *Orderbook) setBidLimitsCache(limit *LimitOrder, price decimal.Decimal) {
+// returns the next book-wide event sequence number
+func (this *Orderbook) nextSeq() uint64 {
+	this.seq++
+	return this.seq
+}
+
+func (this *Orderbook) getBidLimitsCacheByPrice(price int64) *LimitOrder {
+	this.bidLimtRwLock.RLock()
+	defer this.bidLimtRwLock.RUnlock()
+	return this.bidLimitsCache[price]
+}
+
+func (this *Orderbook) getAskLimitsCacheByPrice(price int64) *LimitOrder {
+	this.askLimtRwLock.RLock()
+	defer this.askLimtRwLock.RUnlock()
+	return this.askLimitsCache[price]
+}
+
+func (this *Orderbook) setBidLimitsCache(limit *LimitOrder, price int64) {
 	this.bidLimtRwLock.Lock()
 	defer this.bidLimtRwLock.Unlock()
 	this.bidLimitsCache[price] = limit
 }
-func (this *Orderbook) setAskLimitsCache(limit *LimitOrder, price decimal.Decimal) {
+func (this *Orderbook) setAskLimitsCache(limit *LimitOrder, price int64) {
 	this.askLimtRwLock.Lock()
 	defer this.askLimtRwLock.Unlock()
 	this.askLimitsCache[price] = limit
 }
 
-func (this *Orderbook) deleteBidLimitsCache(price decimal.Decimal) {
-	for k := range this.bidLimitsCache {
-		if k.Equal(price) {
-			this.bidLimtRwLock.Lock()
-			delete(this.bidLimitsCache, k)
-			this.bidLimtRwLock.Unlock()
-		}
+func (this *Orderbook) deleteBidLimitsCache(price int64) {
+	this.bidLimtRwLock.Lock()
+	defer this.bidLimtRwLock.Unlock()
+	delete(this.bidLimitsCache, price)
+}
+func (this *Orderbook) deleteAskLimitsCache(price int64) {
+	this.askLimtRwLock.Lock()
+	defer this.askLimtRwLock.Unlock()
+	delete(this.askLimitsCache, price)
+}
+
+// GetOrder returns a resting order by its id or nil if there is no such order
+func (this *Orderbook) GetOrder(id int) *Order {
+	this.ordersRwLock.RLock()
+	defer this.ordersRwLock.RUnlock()
+	return this.orders[id]
+}
+
+func (this *Orderbook) setOrder(o *Order) {
+	this.scheduleExpiry(o)
+	this.listPeg(o)
+
+	this.ordersRwLock.Lock()
+	defer this.ordersRwLock.Unlock()
+	this.orders[o.Id] = o
+}
+
+func (this *Orderbook) deleteOrder(o *Order) {
+	this.unscheduleExpiry(o)
+	this.unlistPeg(o)
+	this.leave(o)
+
+	this.ordersRwLock.Lock()
+	defer this.ordersRwLock.Unlock()
+	delete(this.orders, o.Id)
+}
+
+// converts the order volume to lots of the book instrument, orders with less
+// than a lot or out of range are rejected. Orders already in the book are
+// rejected before they are changed.
+func (this *Orderbook) admit(o *Order) error {
+	lots, err := this.inst.orderLots(o.Volume)
+	if err != nil {
+		return err
 	}
+	return this.admitLots(o, lots)
 }
-func (this *Orderbook) deleteAskLimitsCache(price decimal.Decimal) {
-	for k := range this.askLimitsCache {
-		if k.Equal(price) {
-			this.askLimtRwLock.Lock()
-			delete(this.askLimitsCache, k)
-			this.askLimtRwLock.Unlock()
-		}
+
+func (this *Orderbook) admitLots(o *Order, lots int64) error {
+	if this.known(o) {
+		return ErrDuplicateId
+	}
+	if lots <= 0 {
+		return ErrInvalidVolume
+	}
+
+	o.admitLots(this.inst, lots)
+	return nil
+}
+
+// reports if an order with the id rests in the book or waits as a stop order
+func (this *Orderbook) known(o *Order) bool {
+	return this.GetOrder(o.Id) != nil || this.GetStop(o.Id) != nil
+}
+
+// reports if the order itself rests in the book or waits as a stop order
+func (this *Orderbook) holds(o *Order) bool {
+	return this.GetOrder(o.Id) == o || this.GetStop(o.Id) == o
+}
+
+// Add rests the order at the price without matching it. The price has to be on
+// the tick grid and the volume at least one lot.
+func (this *Orderbook) Add(price decimal.Decimal, o *Order) error {
+	ticks, err := this.inst.ExactTicks(price)
+	if err != nil {
+		return err
 	}
+	lots, err := this.inst.orderLots(o.Volume)
+	if err != nil {
+		return err
+	}
+	return this.AddTicks(ticks, lots, o)
 }
 
-func (this *Orderbook) Add(price decimal.Decimal, o *Order) {
+// AddTicks is Add with the price in ticks and the volume in lots of the book
+// instrument, the order Volume is not used. Unlike Add it does not allocate in
+// steady state.
+func (this *Orderbook) AddTicks(price, lots int64, o *Order) error {
+	if err := this.admitLots(o, lots); err != nil {
+		return err
+	}
+
+	this.expire()
+	err := this.add(price, o)
+	this.trigger()
+	return err
+}
+
+func (this *Orderbook) add(price int64, o *Order) error {
+	if this.GetOrder(o.Id) != nil || this.GetStop(o.Id) != nil {
+		return ErrDuplicateId
+	}
+
+	this.rest(price, o)
+	this.setOrder(o)
+	return nil
+}
+
+// enqueues the order at the limit of the price
+func (this *Orderbook) rest(price int64, o *Order) {
 	var limit *LimitOrder
 
 	if o.BidOrAsk {
@@ -98,7 +360,9 @@ func (this *Orderbook) Add(price decimal.Decimal, o *Order) {
 	if limit == nil {
 		// getting a new limit from pool
 		limit = this.pool.Get().(*LimitOrder)
-		limit.Price = price
+		limit.Price = this.price(price)
+		limit.ticks = price
+		limit.inst = this.inst
 
 		// insert into the corresponding BST and cache
 		if o.BidOrAsk {
@@ -112,36 +376,600 @@ func (this *Orderbook) Add(price decimal.Decimal, o *Order) {
 
 	// add order to the limit
 	limit.Enqueue(o)
+	this.publish(limit, o.BidOrAsk)
+}
+
+// Cancel removes a resting order from the book
+func (this *Orderbook) Cancel(o *Order) error {
+	if o.Limit == nil || this.GetOrder(o.Id) != o {
+		return ErrUnknownOrder
+	}
+
+	this.cancel(o)
+	this.trigger()
+	return nil
+}
+
+func (this *Orderbook) cancel(o *Order) {
+	this.unrest(o)
+	this.deleteOrder(o)
 }
 
-func (this *Orderbook) Cancel(o *Order) {
+// removes the order from its limit
+func (this *Orderbook) unrest(o *Order) {
 	limit := o.Limit
 	limit.Delete(o)
+	this.publish(limit, o.BidOrAsk)
 
 	if limit.Size() == 0 {
 		// remove the limit if there are no orders
-		if o.BidOrAsk {
-			this.Bids.Delete(limit.Price)
-			this.deleteBidLimitsCache(limit.Price)
-		} else {
-			this.Asks.Delete(limit.Price)
-			this.deleteAskLimitsCache(limit.Price)
+		this.removeLimit(limit, o.BidOrAsk)
+	}
+}
+
+// CancelById cancels a resting or an untriggered stop order by its id
+func (this *Orderbook) CancelById(id int) error {
+	o := this.GetOrder(id)
+	if o == nil {
+		if o = this.GetStop(id); o != nil {
+			this.cancelStop(o)
+			this.trigger()
+			return nil
+		}
+		return ErrUnknownOrder
+	}
+
+	return this.Cancel(o)
+}
+
+// Amend changes price and volume of a resting order. Decreasing the volume at the
+// same price keeps the order queue priority, an increase or a price change
+// re-enters the order at the back of the queue. A price crossing the book is
+// rejected with ErrAmendCross, pegged orders are priced by the book and cannot
+// be amended.
+func (this *Orderbook) Amend(id int, price, volume decimal.Decimal) error {
+	lots, err := this.inst.orderLots(volume)
+	if err != nil {
+		return err
+	}
+	if lots <= 0 {
+		return ErrInvalidVolume
+	}
+
+	ticks, err := this.inst.ExactTicks(price)
+	if err != nil {
+		return err
+	}
+
+	o := this.GetOrder(id)
+	if o == nil {
+		return ErrUnknownOrder
+	}
+	if o.pegged {
+		return ErrOrderPegged
+	}
+
+	if best := this.bestOpposite(o.BidOrAsk); best != nil && crosses(o.BidOrAsk, ticks, best.ticks) {
+		return ErrAmendCross
+	}
+
+	if o.Limit.ticks == ticks && lots <= o.lots+o.hidden {
+		o.Limit.Reduce(o, lots)
+		o.Volume = volume
+		this.publish(o.Limit, o.BidOrAsk)
+		return nil
+	}
+
+	// cancel and replace
+	this.cancel(o)
+	o.Volume = volume
+	o.admit(this.inst)
+	err = this.add(ticks, o)
+	this.trigger()
+	return err
+}
+
+// Match crosses an incoming limit order against the opposite side of the book,
+// consuming resting orders FIFO from the best price level. The residual volume,
+// if any, rests in the book at the order price unless the order time in force
+// is IOC or FOK. Resting all-or-none orders larger than the incoming order are
+// skipped. An order which cannot execute its MinVolume at once is cancelled, an
+// all-or-none order which cannot be filled completely rests without trading.
+// Stop orders triggered by the trades are executed afterwards, their trades are
+// reported to trade handlers only.
+func (this *Orderbook) Match(price decimal.Decimal, o *Order) ([]Trade, error) {
+	ticks, err := this.inst.ExactTicks(price)
+	if err != nil {
+		return nil, err
+	}
+	lots, err := this.inst.orderLots(o.Volume)
+	if err != nil {
+		return nil, err
+	}
+	return this.MatchTicks(ticks, lots, o, nil)
+}
+
+// MatchTicks is Match with the price in ticks and the volume in lots of the book
+// instrument, the order Volume is not used. Trades of the order are appended to
+// trades, so unlike Match it does not allocate in steady state when the slice
+// is reused.
+func (this *Orderbook) MatchTicks(price, lots int64, o *Order, trades []Trade) ([]Trade, error) {
+	if err := this.admitLots(o, lots); err != nil {
+		return trades, err
+	}
+
+	this.expire()
+	trades, err := this.match(price, o, trades)
+	if err == ErrCannotFill {
+		// the order is cancelled as documented
+		err = nil
+	}
+	this.trigger()
+	return trades, err
+}
+
+// appends trades of the order to trades
+func (this *Orderbook) match(price int64, o *Order, trades []Trade) ([]Trade, error) {
+	if this.GetOrder(o.Id) != nil || this.GetStop(o.Id) != nil {
+		return trades, ErrDuplicateId
+	}
+	// detached once settled unless it rests
+	this.leave(o)
+
+	if o.PostOnly {
+		best := this.bestOpposite(o.BidOrAsk)
+		if best != nil && crosses(o.BidOrAsk, price, best.ticks) {
+			if this.postOnly == PostOnlyReject {
+				return trades, ErrPostOnlyCross
+			}
+
+			// slide to the best price not crossing the book
+			price = this.passive(price, o.BidOrAsk)
+		}
+	}
+
+	if required := o.required(); required > 0 && !this.canFill(price, o, required) {
+		if o.AON && o.TIF == GTC {
+			// rests until it can be filled completely
+			return trades, this.add(price, o)
+		}
+		return trades, ErrCannotFill
+	}
+
+	cancelled := false
+
+	// all-or-none orders can be skipped leaving limits not empty, so the limits are
+	// walked from the best price and empty ones are removed afterwards
+	for limit := this.bestOpposite(o.BidOrAsk); limit != nil && o.lots > 0 && !cancelled && crosses(o.BidOrAsk, price, limit.ticks); limit = this.worse(limit, !o.BidOrAsk) {
+		lots, size := limit.totalVolume, limit.Size()
+
+		trades, cancelled = this.matchLimit(limit, o, trades)
+		if limit.totalVolume != lots || limit.Size() != size {
+			this.publish(limit, !o.BidOrAsk)
+		}
+		if limit.Size() == 0 {
+			this.emptied = append(this.emptied, limit)
+		}
+	}
+	this.removeEmptied(!o.BidOrAsk)
+
+	if o.lots > 0 && o.TIF == GTC && !cancelled {
+		this.add(price, o)
+	}
+
+	return trades, nil
+}
+
+// looks ahead over the opposite side up to the order price without changing it
+// and checks if at least the required lots of the order can be executed
+func (this *Orderbook) canFill(price int64, o *Order, required int64) bool {
+	var available int64
+	left := o.lots // lots of the order not traded or cancelled yet
+	for limit := this.bestOpposite(o.BidOrAsk); limit != nil && left > 0 && crosses(o.BidOrAsk, price, limit.ticks); limit = this.worse(limit, !o.BidOrAsk) {
+		if limit.aon == 0 && o.Owner == 0 {
+			lots := min(limit.totalVolume+limit.hiddenVolume, left)
+			available += lots
+			left -= lots
+			continue
+		}
+
+		// same as the matcher, skip all-or-none orders larger than the rest of the
+		// order and apply self-trade prevention to orders of the owner. Iceberg
+		// reserves are replenished at the back of the queue, so they are counted
+		// after the displayed volume of the limit.
+		var hidden int64
+		for maker := limit.Peek(); maker != nil && left > 0; maker = limit.Next(maker) {
+			open := maker.lots + maker.hidden
+			if maker.AON && open > left {
+				continue
+			}
+
+			if selfTrade(maker, o) {
+				switch this.stp {
+				case STPCancelNewest, STPCancelBoth:
+					// the rest of the order is cancelled
+					return available >= required
+				case STPDecrementAndCancel:
+					left -= min(open, left)
+				}
+				continue
+			}
+
+			lots := min(maker.lots, left)
+			available += lots
+			left -= lots
+			hidden += maker.hidden
 		}
 
-		// put it back to the pool
-		this.pool.Put(limit)
+		lots := min(hidden, left)
+		available += lots
+		left -= lots
+	}
+	return available >= required
+}
+
+// fills the incoming order against the limit queue until either one is exhausted
+// skipping all-or-none orders larger than the rest of the incoming order, reports
+// if the rest of the incoming order is cancelled by self-trade prevention
+func (this *Orderbook) matchLimit(limit *LimitOrder, o *Order, trades []Trade) ([]Trade, bool) {
+	for maker := limit.Peek(); maker != nil && o.lots > 0; {
+		next := limit.Next(maker)
+
+		if maker.AON && maker.lots+maker.hidden > o.lots {
+			maker = next
+			continue
+		}
+
+		if selfTrade(maker, o) {
+			if this.preventSelfTrade(limit, maker, o) {
+				return trades, true
+			}
+			maker = next
+			continue
+		}
+
+		lots := min(o.lots, maker.lots)
+		trades = this.execute(limit, maker, o, lots, trades)
+		o.lots -= lots
+
+		maker = nextMaker(limit, maker, next)
+	}
+
+	return trades, false
+}
+
+// returns the order to match after a traded maker, a replenished iceberg order
+// is visited again at the back of the queue
+func nextMaker(limit *LimitOrder, maker, next *Order) *Order {
+	if next == nil && maker.Limit == limit {
+		return maker
+	}
+	return next
+}
+
+// checks if the orders belong to the same owner
+func selfTrade(maker, taker *Order) bool {
+	return taker.Owner != 0 && maker.Owner == taker.Owner
+}
+
+// applies the self-trade prevention policy instead of trading lots between orders
+// of the same owner, returns true if the rest of the incoming order is cancelled
+func (this *Orderbook) preventSelfTrade(limit *LimitOrder, maker, taker *Order) bool {
+	switch this.stp {
+	case STPCancelNewest:
+		return true
+	case STPCancelOldest:
+		this.discard(limit, maker)
+		return false
+	case STPCancelBoth:
+		this.discard(limit, maker)
+		return true
+	}
+
+	// decrement both by the smaller open volume
+	lots := min(taker.lots, maker.lots+maker.hidden)
+	limit.Reduce(maker, maker.lots+maker.hidden-lots)
+	if maker.lots+maker.hidden <= 0 {
+		this.discard(limit, maker)
+	}
+	taker.lots -= lots
+	return taker.lots <= 0
+}
+
+// removes a resting order from the limit being matched, the caller publishes
+// and removes the limit
+func (this *Orderbook) discard(limit *LimitOrder, o *Order) {
+	limit.Delete(o)
+	this.deleteOrder(o)
+}
+
+// executes lots between a resting maker order and an incoming taker order
+func (this *Orderbook) execute(limit *LimitOrder, maker, taker *Order, lots int64, trades []Trade) []Trade {
+	limit.Fill(maker, lots)
+	if maker.lots <= 0 {
+		this.deleteOrder(maker)
+	}
+	if maker.group != nil {
+		this.filled = append(this.filled, maker)
+	}
+	if taker.group != nil {
+		this.filled = append(this.filled, taker)
+	}
+	this.last = limit.ticks
+	this.traded = true
+
+	trade := Trade{
+		Seq:      this.nextSeq(),
+		MakerId:  maker.Id,
+		TakerId:  taker.Id,
+		Price:    limit.Price,
+		Lots:     lots,
+		BidOrAsk: taker.BidOrAsk,
+		inst:     this.inst,
 	}
+	for _, h := range this.tradeHandlers {
+		h(trade)
+	}
+
+	return append(trades, trade)
 }
 
-func (this *Orderbook) ClearBidLimit(price decimal.Decimal) {
-	this.clearLimit(price, true)
+// Aggregated outcome of a market order execution
+type MarketResult struct {
+	Filled   decimal.Decimal // executed volume
+	Notional decimal.Decimal // executed quote volume, sum of price * volume
+	AvgPrice decimal.Decimal // volume weighted average execution price
+	Levels   int             // number of limits the order traded at
+	Unfilled decimal.Decimal // remaining volume, or remaining notional for MarketQuote
+	Trades   []Trade
 }
 
-func (this *Orderbook) ClearAskLimit(price decimal.Decimal) {
-	this.clearLimit(price, false)
+// Market executes the order volume against the opposite side of the book until
+// it is filled or the side is exhausted. The unfilled remainder never rests. An
+// order with MinVolume, all-or-none or FOK is not executed if there is not enough
+// volume.
+func (this *Orderbook) Market(o *Order) MarketResult {
+	res := MarketResult{
+		Filled:   decimal.Zero,
+		Notional: decimal.Zero,
+		AvgPrice: decimal.Zero,
+	}
+	if this.holds(o) {
+		// orders in the book are not changed
+		res.Unfilled = o.Remaining()
+		return res
+	}
+
+	this.expire()
+	o.admit(this.inst)
+	if required := o.required(); required == 0 || this.canFill(anyPrice(o.BidOrAsk), o, required) {
+		res = this.market(o, decimal.Zero, false)
+	}
+	res.Unfilled = o.Remaining()
+	this.trigger()
+	return res
 }
 
-func (this *Orderbook) clearLimit(price decimal.Decimal, bidOrAsk bool) {
+// MarketQuote executes the order against the opposite side of the book until
+// the quote notional is spent or the side is exhausted. The order volume is not used.
+// The execution stops at a limit with a non-positive price.
+func (this *Orderbook) MarketQuote(o *Order, notional decimal.Decimal) MarketResult {
+	if this.holds(o) {
+		// orders in the book are not changed
+		return MarketResult{
+			Filled:   decimal.Zero,
+			Notional: decimal.Zero,
+			AvgPrice: decimal.Zero,
+			Unfilled: notional,
+		}
+	}
+
+	this.expire()
+	o.admit(this.inst)
+	res := this.market(o, notional, true)
+	res.Unfilled = notional.Sub(res.Notional)
+	this.trigger()
+	return res
+}
+
+func (this *Orderbook) market(o *Order, notional decimal.Decimal, byQuote bool) MarketResult {
+	res := MarketResult{
+		Notional: decimal.Zero,
+		AvgPrice: decimal.Zero,
+	}
+	var filled int64
+
+	// walk the limits from the best price, empty limits are removed afterwards
+	// as deleting from the tree can relocate nodes
+	done := false
+	for limit := this.bestOpposite(o.BidOrAsk); limit != nil && !done; limit = this.worse(limit, !o.BidOrAsk) {
+		var traded int64
+		prevented := false
+
+		for maker := limit.Peek(); maker != nil; {
+			next := limit.Next(maker)
+
+			// lots the order can take
+			capacity := o.lots
+			if byQuote && limit.ticks <= 0 {
+				// the notional cannot be converted to volume at the price
+				capacity = 0
+			} else if byQuote {
+				spent := res.Notional.Add(limit.Price.Mul(this.inst.Volume(traded)))
+				capacity = this.inst.Lots(notional.Sub(spent).Div(limit.Price))
+			}
+			if capacity <= 0 {
+				done = true
+				break
+			}
+
+			if maker.AON && maker.lots+maker.hidden > capacity {
+				maker = next
+				continue
+			}
+
+			if selfTrade(maker, o) {
+				prevented = true
+
+				// the quote order has no volume to decrement, it is cancelled
+				if byQuote && this.stp == STPDecrementAndCancel || this.preventSelfTrade(limit, maker, o) {
+					done = true
+					break
+				}
+				maker = next
+				continue
+			}
+
+			lots := min(capacity, maker.lots)
+			res.Trades = this.execute(limit, maker, o, lots, res.Trades)
+			traded += lots
+			if !byQuote {
+				o.lots -= lots
+			}
+
+			maker = nextMaker(limit, maker, next)
+		}
+
+		if traded > 0 || prevented {
+			this.publish(limit, !o.BidOrAsk)
+		}
+		if traded > 0 {
+			res.Levels++
+			res.Notional = res.Notional.Add(limit.Price.Mul(this.inst.Volume(traded)))
+			filled += traded
+		}
+		if limit.Size() == 0 {
+			this.emptied = append(this.emptied, limit)
+		}
+	}
+	this.removeEmptied(!o.BidOrAsk)
+
+	res.Filled = this.inst.Volume(filled)
+	if filled > 0 {
+		res.AvgPrice = res.Notional.Div(res.Filled)
+	}
+
+	return res
+}
+
+// returns the best limit of the side or nil if it is empty
+func (this *Orderbook) best(bidOrAsk bool) *LimitOrder {
+	if bidOrAsk {
+		if this.Bids.IsEmpty() {
+			return nil
+		}
+		return this.Bids.MaxValue()
+	}
+
+	if this.Asks.IsEmpty() {
+		return nil
+	}
+	return this.Asks.MinValue()
+}
+
+// steps to the next worse limit of the side, lower for bids and higher for asks
+func (this *Orderbook) worse(limit *LimitOrder, bidOrAsk bool) *LimitOrder {
+	if bidOrAsk {
+		return this.Bids.Lower(limit.ticks)
+	}
+	return this.Asks.Higher(limit.ticks)
+}
+
+// returns the best limit of the side opposite to the order side or nil if it is empty
+func (this *Orderbook) bestOpposite(bidOrAsk bool) *LimitOrder {
+	return this.best(!bidOrAsk)
+}
+
+// returns the price moved to one tick from the best opposite limit if it crosses the book
+func (this *Orderbook) passive(price int64, bidOrAsk bool) int64 {
+	best := this.bestOpposite(bidOrAsk)
+	if best == nil || !crosses(bidOrAsk, price, best.ticks) {
+		return price
+	}
+
+	if bidOrAsk {
+		return best.ticks - 1
+	}
+	return best.ticks + 1
+}
+
+// returns the order price crossing every opposite limit
+func anyPrice(bidOrAsk bool) int64 {
+	if bidOrAsk {
+		return math.MaxInt64
+	}
+	return math.MinInt64
+}
+
+// checks if an order price reaches the opposite limit price
+func crosses(bidOrAsk bool, price, limitPrice int64) bool {
+	if bidOrAsk {
+		return price >= limitPrice
+	}
+	return price <= limitPrice
+}
+
+// removes limits emptied while walking the side
+func (this *Orderbook) removeEmptied(bidOrAsk bool) {
+	for _, limit := range this.emptied {
+		this.removeLimit(limit, bidOrAsk)
+	}
+	clear(this.emptied)
+	this.emptied = this.emptied[:0]
+}
+
+// returns the decimal price of ticks, prices are cached to re-create limits
+// without allocations
+func (this *Orderbook) price(ticks int64) decimal.Decimal {
+	if price, ok := this.prices[ticks]; ok {
+		return price
+	}
+
+	if len(this.prices) >= PriceCacheSize {
+		clear(this.prices)
+	}
+	price := this.inst.Price(ticks)
+	this.prices[ticks] = price
+	return price
+}
+
+// removes an empty limit from the corresponding BST and cache
+func (this *Orderbook) removeLimit(limit *LimitOrder, bidOrAsk bool) {
+	if bidOrAsk {
+		this.Bids.Delete(limit.ticks)
+		this.deleteBidLimitsCache(limit.ticks)
+	} else {
+		this.Asks.Delete(limit.ticks)
+		this.deleteAskLimitsCache(limit.ticks)
+	}
+
+	// put it back to the pool
+	this.pool.Put(limit)
+}
+
+// ClearBidLimit removes all orders of the bid limit keeping the limit in the book
+func (this *Orderbook) ClearBidLimit(price decimal.Decimal) error {
+	ticks, err := this.inst.ExactTicks(price)
+	if err != nil {
+		return err
+	}
+	err = this.clearLimit(ticks, true)
+	this.trigger()
+	return err
+}
+
+// ClearAskLimit removes all orders of the ask limit keeping the limit in the book
+func (this *Orderbook) ClearAskLimit(price decimal.Decimal) error {
+	ticks, err := this.inst.ExactTicks(price)
+	if err != nil {
+		return err
+	}
+	err = this.clearLimit(ticks, false)
+	this.trigger()
+	return err
+}
+
+func (this *Orderbook) clearLimit(price int64, bidOrAsk bool) error {
 	var limit *LimitOrder
 	if bidOrAsk {
 		limit = this.getBidLimitsCacheByPrice(price)
@@ -150,51 +978,74 @@ func (this *Orderbook) clearLimit(price decimal.Decimal, bidOrAsk bool) {
 	}
 
 	if limit == nil {
-		panic(fmt.Sprintf("there is no such price limit %+v", price))
+		return ErrUnknownLevel
 	}
 
+	limit.Each(this.deleteOrder)
 	limit.Clear()
+	this.publish(limit, bidOrAsk)
+	return nil
 }
 
-func (this *Orderbook) DeleteBidLimit(price decimal.Decimal) {
-	limit := this.getBidLimitsCacheByPrice(price)
+// DeleteBidLimit removes the bid limit with all its orders from the book
+func (this *Orderbook) DeleteBidLimit(price decimal.Decimal) error {
+	ticks, err := this.inst.ExactTicks(price)
+	if err != nil {
+		return err
+	}
+	limit := this.getBidLimitsCacheByPrice(ticks)
 	if limit == nil {
-		return
+		return ErrUnknownLevel
 	}
 
-	this.deleteLimit(price, true)
-	this.deleteBidLimitsCache(price)
+	this.deleteLimit(ticks, true)
+	this.deleteBidLimitsCache(ticks)
 
 	// put limit back to the pool
+	limit.Each(this.deleteOrder)
 	limit.Clear()
+	this.publish(limit, true)
 	this.pool.Put(limit)
-
+	this.trigger()
+	return nil
 }
 
-func (this *Orderbook) DeleteAskLimit(price decimal.Decimal) {
-	limit := this.getAskLimitsCacheByPrice(price)
+// DeleteAskLimit removes the ask limit with all its orders from the book
+func (this *Orderbook) DeleteAskLimit(price decimal.Decimal) error {
+	ticks, err := this.inst.ExactTicks(price)
+	if err != nil {
+		return err
+	}
+	limit := this.getAskLimitsCacheByPrice(ticks)
 	if limit == nil {
-		return
+		return ErrUnknownLevel
 	}
 
-	this.deleteLimit(price, false)
-	this.deleteAskLimitsCache(price)
+	this.deleteLimit(ticks, false)
+	this.deleteAskLimitsCache(ticks)
 
 	// put limit back to the pool
+	limit.Each(this.deleteOrder)
 	limit.Clear()
+	this.publish(limit, false)
 	this.pool.Put(limit)
+	this.trigger()
+	return nil
 }
 
-func (this *Orderbook) deleteLimit(price decimal.Decimal, bidOrAsk bool) {
+func (this *Orderbook) deleteLimit(price int64, bidOrAsk bool) error {
 	if bidOrAsk {
-		this.Bids.Delete(price)
-	} else {
-		this.Asks.Delete(price)
+		return this.Bids.Delete(price)
 	}
+	return this.Asks.Delete(price)
 }
 
 func (this *Orderbook) GetVolumeAtBidLimit(price decimal.Decimal) decimal.Decimal {
-	limit := this.getBidLimitsCacheByPrice(price)
+	ticks, err := this.inst.ExactTicks(price)
+	if err != nil {
+		return decimal.Zero
+	}
+	limit := this.getBidLimitsCacheByPrice(ticks)
 	if limit == nil {
 		return decimal.Zero
 	}
@@ -202,19 +1053,41 @@ func (this *Orderbook) GetVolumeAtBidLimit(price decimal.Decimal) decimal.Decima
 }
 
 func (this *Orderbook) GetVolumeAtAskLimit(price decimal.Decimal) decimal.Decimal {
-	limit := this.getAskLimitsCacheByPrice(price)
+	ticks, err := this.inst.ExactTicks(price)
+	if err != nil {
+		return decimal.Zero
+	}
+	limit := this.getAskLimitsCacheByPrice(ticks)
 	if limit == nil {
 		return decimal.Zero
 	}
 	return limit.TotalVolume()
 }
 
+// GetBestBid returns the highest bid price, it panics if there are no bids, see BestBid
 func (this *Orderbook) GetBestBid() decimal.Decimal {
-	return this.Bids.Max()
+	return this.Bids.MaxValue().Price
 }
 
+// GetBestOffer returns the lowest ask price, it panics if there are no asks, see BestOffer
 func (this *Orderbook) GetBestOffer() decimal.Decimal {
-	return this.Asks.Min()
+	return this.Asks.MinValue().Price
+}
+
+// BestBid returns the highest bid price or ErrEmptySide if there are no bids
+func (this *Orderbook) BestBid() (decimal.Decimal, error) {
+	if this.Bids.IsEmpty() {
+		return decimal.Zero, ErrEmptySide
+	}
+	return this.Bids.MaxValue().Price, nil
+}
+
+// BestOffer returns the lowest ask price or ErrEmptySide if there are no asks
+func (this *Orderbook) BestOffer() (decimal.Decimal, error) {
+	if this.Asks.IsEmpty() {
+		return decimal.Zero, ErrEmptySide
+	}
+	return this.Asks.MinValue().Price, nil
 }
 
 func (this *Orderbook) BLength() int {
@@ -20,10 +20,14 @@ func TestOrderbookEmpty(t *testing.T) {
 func TestOrderbookAddOne(t *testing.T) {
 	b := NewOrderbook()
 	bid := &Order{
+		Id:       1,
 		BidOrAsk: true,
+		Volume:   decimal.NewFromInt(1),
 	}
 	ask := &Order{
+		Id:       2,
 		BidOrAsk: false,
+		Volume:   decimal.NewFromInt(1),
 	}
 	b.Add(decimal.NewFromFloat(1.0), bid)
 	b.Add(decimal.NewFromFloat(2.0), ask)
@@ -39,14 +43,18 @@ func TestOrderbookAddMultiple(t *testing.T) {
 	b := NewOrderbook()
 	for i := 0; i < 100; i += 1 {
 		bid := &Order{
+			Id:       i,
 			BidOrAsk: true,
+			Volume:   decimal.NewFromInt(1),
 		}
 		b.Add(decimal.NewFromInt(int64(i)), bid)
 	}
 
 	for i := 100; i < 200; i += 1 {
 		bid := &Order{
+			Id:       i,
 			BidOrAsk: false,
+			Volume:   decimal.NewFromInt(1),
 		}
 		b.Add(decimal.NewFromInt(int64(i)), bid)
 	}
@@ -74,10 +82,12 @@ func TestOrderbookAddAndCancel(t *testing.T) {
 	bid1 := &Order{
 		Id:       1,
 		BidOrAsk: true,
+		Volume:   decimal.NewFromInt(1),
 	}
 	bid2 := &Order{
 		Id:       2,
 		BidOrAsk: true,
+		Volume:   decimal.NewFromInt(1),
 	}
 	b.Add(decimal.NewFromFloat(1.0), bid1)
 	b.Add(decimal.NewFromFloat(2.0), bid2)
@@ -113,6 +123,677 @@ func TestGetVolumeAtLimit(t *testing.T) {
 	}
 }
 
+func TestOrderbookMatchNoCross(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromFloat(2.0), &Order{Id: 1, Volume: decimal.NewFromFloat(1.0)})
+
+	bid := &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)}
+	trades, _ := b.Match(decimal.NewFromFloat(1.0), bid)
+	if len(trades) != 0 {
+		t.Errorf("there should be no trades")
+	}
+	if b.BLength() != 1 || b.ALength() != 1 {
+		t.Errorf("both orders should rest in the book")
+	}
+	if bid.Limit == nil {
+		t.Errorf("bid should rest at its limit")
+	}
+}
+
+func TestOrderbookMatchFIFO(t *testing.T) {
+	b := NewOrderbook()
+	ask1 := &Order{Id: 1, Volume: decimal.NewFromFloat(1.0)}
+	ask2 := &Order{Id: 2, Volume: decimal.NewFromFloat(2.0)}
+	b.Add(decimal.NewFromFloat(10.0), ask1)
+	b.Add(decimal.NewFromFloat(10.0), ask2)
+
+	bid := &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromFloat(1.5)}
+	trades, _ := b.Match(decimal.NewFromFloat(10.0), bid)
+	if len(trades) != 2 {
+		t.Fatalf("there should be 2 trades, got %d", len(trades))
+	}
+	if trades[0].MakerId != 1 || !trades[0].Volume().Equal(decimal.NewFromFloat(1.0)) {
+		t.Errorf("the oldest order should be filled first: %+v", trades[0])
+	}
+	if trades[1].MakerId != 2 || !trades[1].Volume().Equal(decimal.NewFromFloat(0.5)) {
+		t.Errorf("the second order should be partially filled: %+v", trades[1])
+	}
+	if trades[0].TakerId != 3 || !trades[0].Price.Equal(decimal.NewFromFloat(10.0)) {
+		t.Errorf("invalid trade: %+v", trades[0])
+	}
+	if ask1.Limit != nil {
+		t.Errorf("filled order should leave the limit")
+	}
+	if !ask2.Remaining().Equal(decimal.NewFromFloat(1.5)) {
+		t.Errorf("invalid remaining volume: %+v", ask2.Remaining())
+	}
+	if !b.GetVolumeAtAskLimit(decimal.NewFromFloat(10.0)).Equal(decimal.NewFromFloat(1.5)) {
+		t.Errorf("invalid volume at limit: %+v", b.GetVolumeAtAskLimit(decimal.NewFromFloat(10.0)))
+	}
+	if b.BLength() != 0 {
+		t.Errorf("fully filled bid should not rest")
+	}
+}
+
+func TestOrderbookMatchMultipleLevels(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromFloat(10.0), &Order{Id: 1, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.NewFromFloat(11.0), &Order{Id: 2, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.NewFromFloat(12.0), &Order{Id: 3, Volume: decimal.NewFromFloat(1.0)})
+
+	bid := &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromFloat(3.0)}
+	trades, _ := b.Match(decimal.NewFromFloat(11.0), bid)
+	if len(trades) != 2 {
+		t.Fatalf("there should be 2 trades, got %d", len(trades))
+	}
+	if !trades[0].Price.Equal(decimal.NewFromFloat(10.0)) || !trades[1].Price.Equal(decimal.NewFromFloat(11.0)) {
+		t.Errorf("levels should be consumed from the best price")
+	}
+	if b.ALength() != 1 || !b.GetBestOffer().Equal(decimal.NewFromFloat(12.0)) {
+		t.Errorf("best offer should be 12.0 now")
+	}
+	if b.BLength() != 1 || !b.GetBestBid().Equal(decimal.NewFromFloat(11.0)) {
+		t.Errorf("residual should rest at 11.0")
+	}
+	if !b.GetVolumeAtBidLimit(decimal.NewFromFloat(11.0)).Equal(decimal.NewFromFloat(1.0)) {
+		t.Errorf("invalid residual volume")
+	}
+}
+
+func TestOrderbookMatchAsk(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromFloat(10.0), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.NewFromFloat(9.0), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+
+	ask := &Order{Id: 3, Volume: decimal.NewFromFloat(2.0)}
+	trades, _ := b.Match(decimal.NewFromFloat(9.0), ask)
+	if len(trades) != 2 {
+		t.Fatalf("there should be 2 trades, got %d", len(trades))
+	}
+	if trades[0].MakerId != 1 || trades[1].MakerId != 2 {
+		t.Errorf("bids should be consumed from the highest price")
+	}
+	if b.BLength() != 0 || b.ALength() != 0 {
+		t.Errorf("book should be empty")
+	}
+}
+
+func TestOrderbookMarketWalksLevels(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromFloat(10.0), &Order{Id: 1, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.NewFromFloat(11.0), &Order{Id: 2, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.NewFromFloat(12.0), &Order{Id: 3, Volume: decimal.NewFromFloat(2.0)})
+
+	o := &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromFloat(3.0)}
+	res := b.Market(o)
+	if !res.Filled.Equal(decimal.NewFromFloat(3.0)) || !res.Unfilled.IsZero() {
+		t.Errorf("order should be fully filled: %+v", res)
+	}
+	if res.Levels != 3 || len(res.Trades) != 3 {
+		t.Errorf("order should touch 3 levels: %+v", res)
+	}
+	if !res.AvgPrice.Equal(decimal.NewFromFloat(11.0)) {
+		t.Errorf("invalid average price: %+v", res.AvgPrice)
+	}
+	if b.ALength() != 1 || !b.GetVolumeAtAskLimit(decimal.NewFromFloat(12.0)).Equal(decimal.NewFromFloat(1.0)) {
+		t.Errorf("only 1.0 should remain at 12.0")
+	}
+	if b.BLength() != 0 {
+		t.Errorf("market order should never rest")
+	}
+}
+
+func TestOrderbookMarketNoLiquidity(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromFloat(10.0), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.NewFromFloat(9.0), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+
+	o := &Order{Id: 3, Volume: decimal.NewFromFloat(5.0)}
+	res := b.Market(o)
+	if !res.Filled.Equal(decimal.NewFromFloat(2.0)) || !res.Unfilled.Equal(decimal.NewFromFloat(3.0)) {
+		t.Errorf("order should be partially filled: %+v", res)
+	}
+	if res.Levels != 2 || !res.AvgPrice.Equal(decimal.NewFromFloat(9.5)) {
+		t.Errorf("invalid execution summary: %+v", res)
+	}
+	if b.BLength() != 0 || b.ALength() != 0 {
+		t.Errorf("book should be empty")
+	}
+
+	res = b.Market(&Order{Id: 4, Volume: decimal.NewFromFloat(1.0)})
+	if !res.Filled.IsZero() || res.Levels != 0 || !res.Unfilled.Equal(decimal.NewFromFloat(1.0)) {
+		t.Errorf("nothing should be filled on empty side: %+v", res)
+	}
+}
+
+func TestOrderbookMarketQuote(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromFloat(10.0), &Order{Id: 1, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.NewFromFloat(20.0), &Order{Id: 2, Volume: decimal.NewFromFloat(1.0)})
+
+	res := b.MarketQuote(&Order{Id: 3, BidOrAsk: true}, decimal.NewFromFloat(20.0))
+	if !res.Filled.Equal(decimal.NewFromFloat(1.5)) || !res.Notional.Equal(decimal.NewFromFloat(20.0)) {
+		t.Errorf("invalid execution: %+v", res)
+	}
+	if !res.Unfilled.IsZero() || res.Levels != 2 {
+		t.Errorf("notional should be spent over 2 levels: %+v", res)
+	}
+	if !b.GetVolumeAtAskLimit(decimal.NewFromFloat(20.0)).Equal(decimal.NewFromFloat(0.5)) {
+		t.Errorf("0.5 should remain at 20.0")
+	}
+}
+
+func TestOrderbookMarketQuoteZeroPrice(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.Zero, &Order{Id: 1, Volume: decimal.NewFromInt(1)})
+
+	res := b.MarketQuote(&Order{Id: 2, BidOrAsk: true}, decimal.NewFromInt(20))
+	if !res.Filled.IsZero() || !res.Unfilled.Equal(decimal.NewFromInt(20)) || b.ALength() != 1 {
+		t.Errorf("notional should not be spent at a zero price: %+v", res)
+	}
+}
+
+func TestOrderbookTradeReporting(t *testing.T) {
+	b := NewOrderbook()
+	var tape []Trade
+	b.OnTrade(func(trade Trade) {
+		tape = append(tape, trade)
+	})
+
+	b.Add(decimal.NewFromFloat(10.0), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.NewFromFloat(11.0), &Order{Id: 2, Volume: decimal.NewFromFloat(1.0)})
+
+	trades, _ := b.Match(decimal.NewFromFloat(10.0), &Order{Id: 3, Volume: decimal.NewFromFloat(0.5)})
+	res := b.Market(&Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+	trades = append(trades, res.Trades...)
+
+	if len(tape) != 2 || len(trades) != 2 {
+		t.Fatalf("there should be 2 trades reported, got %d", len(tape))
+	}
+	for i := range tape {
+		if tape[i] != trades[i] {
+			t.Errorf("handler and returned trades differ: %+v, %+v", tape[i], trades[i])
+		}
+	}
+	if tape[0].MakerId != 1 || tape[0].TakerId != 3 || tape[0].BidOrAsk {
+		t.Errorf("invalid sell aggressor trade: %+v", tape[0])
+	}
+	if tape[1].MakerId != 2 || tape[1].TakerId != 4 || !tape[1].BidOrAsk {
+		t.Errorf("invalid buy aggressor trade: %+v", tape[1])
+	}
+	if tape[1].Seq <= tape[0].Seq {
+		t.Errorf("sequence numbers should increase")
+	}
+}
+
+func TestOrderbookOrderIndex(t *testing.T) {
+	b := NewOrderbook()
+	bid := &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)}
+	if err := b.Add(decimal.NewFromFloat(1.0), bid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.GetOrder(1) != bid {
+		t.Errorf("order should be found by id")
+	}
+
+	dup := &Order{Id: 1, Volume: decimal.NewFromFloat(1.0)}
+	if err := b.Add(decimal.NewFromFloat(2.0), dup); err != ErrDuplicateId {
+		t.Errorf("duplicate id should be rejected, got %v", err)
+	}
+	if _, err := b.Match(decimal.NewFromFloat(2.0), dup); err != ErrDuplicateId {
+		t.Errorf("duplicate id should be rejected, got %v", err)
+	}
+	if b.ALength() != 0 {
+		t.Errorf("rejected order should not rest")
+	}
+
+	if err := b.CancelById(1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if b.GetOrder(1) != nil || b.BLength() != 0 {
+		t.Errorf("order should be cancelled")
+	}
+	if err := b.CancelById(1); err != ErrUnknownOrder {
+		t.Errorf("unknown id should be reported, got %v", err)
+	}
+}
+
+func TestOrderbookOrderIndexSync(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromFloat(1.0), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.NewFromFloat(1.0), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.NewFromFloat(2.0), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.NewFromFloat(3.0), &Order{Id: 4, Volume: decimal.NewFromFloat(1.0)})
+
+	// filled maker leaves the index, the residual taker joins it
+	b.Match(decimal.NewFromFloat(2.0), &Order{Id: 5, Volume: decimal.NewFromFloat(2.0)})
+	if b.GetOrder(3) != nil {
+		t.Errorf("filled order should be removed from index")
+	}
+	if b.GetOrder(5) == nil {
+		t.Errorf("residual order should be indexed")
+	}
+
+	b.ClearBidLimit(decimal.NewFromFloat(1.0))
+	if b.GetOrder(1) != nil || b.GetOrder(2) != nil {
+		t.Errorf("cleared orders should be removed from index")
+	}
+
+	b.DeleteAskLimit(decimal.NewFromFloat(3.0))
+	if b.GetOrder(4) != nil {
+		t.Errorf("deleted orders should be removed from index")
+	}
+}
+
+func TestOrderbookAmendDecrease(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromFloat(1.0), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromFloat(2.0)})
+	b.Add(decimal.NewFromFloat(1.0), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+
+	if err := b.Amend(1, decimal.NewFromFloat(1.0), decimal.NewFromFloat(0.5)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !b.GetVolumeAtBidLimit(decimal.NewFromFloat(1.0)).Equal(decimal.NewFromFloat(1.5)) {
+		t.Errorf("invalid volume at limit: %+v", b.GetVolumeAtBidLimit(decimal.NewFromFloat(1.0)))
+	}
+	if b.Bids.MaxValue().Peek().Id != 1 {
+		t.Errorf("decreased order should keep its priority")
+	}
+}
+
+func TestOrderbookAmendIncrease(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromFloat(1.0), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.NewFromFloat(1.0), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+
+	if err := b.Amend(1, decimal.NewFromFloat(1.0), decimal.NewFromFloat(3.0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !b.GetVolumeAtBidLimit(decimal.NewFromFloat(1.0)).Equal(decimal.NewFromFloat(4.0)) {
+		t.Errorf("invalid volume at limit: %+v", b.GetVolumeAtBidLimit(decimal.NewFromFloat(1.0)))
+	}
+	if b.Bids.MaxValue().Peek().Id != 2 {
+		t.Errorf("increased order should lose its priority")
+	}
+}
+
+func TestOrderbookAmendPrice(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromFloat(1.0), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+
+	if err := b.Amend(1, decimal.NewFromFloat(2.0), decimal.NewFromFloat(1.0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.BLength() != 1 || !b.GetBestBid().Equal(decimal.NewFromFloat(2.0)) {
+		t.Errorf("order should be moved to 2.0")
+	}
+	if b.GetOrder(1).Limit.Price.Cmp(decimal.NewFromFloat(2.0)) != 0 {
+		t.Errorf("order should stay indexed")
+	}
+
+	if err := b.Amend(2, decimal.NewFromFloat(2.0), decimal.NewFromFloat(1.0)); err != ErrUnknownOrder {
+		t.Errorf("unknown id should be reported, got %v", err)
+	}
+	if err := b.Amend(1, decimal.NewFromFloat(2.0), decimal.Zero); err != ErrInvalidVolume {
+		t.Errorf("zero volume should be rejected, got %v", err)
+	}
+}
+
+func TestOrderbookAmendCross(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(12), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+
+	if err := b.Amend(1, decimal.NewFromInt(15), decimal.NewFromInt(1)); err != ErrAmendCross {
+		t.Errorf("crossing amend should be rejected, got %v", err)
+	}
+	if err := b.Amend(2, decimal.NewFromInt(10), decimal.NewFromInt(1)); err != ErrAmendCross {
+		t.Errorf("crossing amend should be rejected, got %v", err)
+	}
+	if !b.GetBestBid().Equal(decimal.NewFromInt(10)) || !b.GetBestOffer().Equal(decimal.NewFromInt(12)) {
+		t.Errorf("rejected amends should not change the book")
+	}
+
+	if err := b.Amend(1, decimal.NewFromInt(11), decimal.NewFromInt(1)); err != nil || !b.GetBestBid().Equal(decimal.NewFromInt(11)) {
+		t.Errorf("amend inside the spread should be accepted, got %v", err)
+	}
+}
+
+func TestOrderbookAmendPegged(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.AddPegged(Peg{Ref: PegPrimary}, &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+
+	if err := b.Amend(2, decimal.NewFromInt(9), decimal.NewFromInt(1)); err != ErrOrderPegged {
+		t.Errorf("pegged order amend should be rejected, got %v", err)
+	}
+	if b.GetOrder(2).Limit.ticks != b.inst.Ticks(decimal.NewFromInt(10)) {
+		t.Errorf("pegged order should stay at the peg price")
+	}
+}
+
+func TestOrderbookCanonicalPriceKey(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.RequireFromString("1.5"), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.RequireFromString("1.500"), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+	b.Add(decimal.New(15, -1), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromFloat(1.0)})
+
+	if b.BLength() != 1 || b.Bids.Size() != 1 {
+		t.Errorf("equal prices should share one limit")
+	}
+	if !b.GetVolumeAtBidLimit(decimal.RequireFromString("1.50")).Equal(decimal.NewFromInt(3)) {
+		t.Errorf("invalid volume at limit: %+v", b.GetVolumeAtBidLimit(decimal.RequireFromString("1.50")))
+	}
+}
+
+func TestOrderbookDeltas(t *testing.T) {
+	b := NewOrderbook()
+	var deltas []Delta
+	b.OnDelta(func(d Delta) {
+		deltas = append(deltas, d)
+	})
+
+	bid := &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)}
+	b.Add(decimal.NewFromInt(10), bid)
+	b.Add(decimal.NewFromInt(10), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromInt(2)})
+	b.Add(decimal.NewFromInt(11), &Order{Id: 3, Volume: decimal.NewFromInt(1)})
+	b.Cancel(bid)
+	b.Match(decimal.NewFromInt(10), &Order{Id: 4, Volume: decimal.NewFromInt(1)})
+	b.DeleteAskLimit(decimal.NewFromInt(11))
+
+	expected := []struct {
+		bidOrAsk bool
+		price    int64
+		volume   int64
+		orders   int
+	}{
+		{true, 10, 1, 1},
+		{true, 10, 3, 2},
+		{false, 11, 1, 1},
+		{true, 10, 2, 1},
+		{true, 10, 1, 1},
+		{false, 11, 0, 0},
+	}
+	if len(deltas) != len(expected) {
+		t.Fatalf("there should be %d deltas, got %d", len(expected), len(deltas))
+	}
+	for i, e := range expected {
+		d := deltas[i]
+		if d.BidOrAsk != e.bidOrAsk || !d.Price.Equal(decimal.NewFromInt(e.price)) ||
+			!d.Volume().Equal(decimal.NewFromInt(e.volume)) || d.Orders != e.orders {
+			t.Errorf("invalid delta %d: %+v", i, d)
+		}
+		if i > 0 && d.Seq <= deltas[i-1].Seq {
+			t.Errorf("sequence numbers should increase")
+		}
+	}
+}
+
+func TestOrderbookDeltasOnMarket(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(11), &Order{Id: 2, Volume: decimal.NewFromInt(2)})
+
+	var deltas []Delta
+	b.OnDelta(func(d Delta) {
+		deltas = append(deltas, d)
+	})
+	b.Market(&Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(2)})
+
+	if len(deltas) != 2 {
+		t.Fatalf("there should be 2 deltas, got %d", len(deltas))
+	}
+	if deltas[0].Orders != 0 || !deltas[0].Price.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("first limit should be removed: %+v", deltas[0])
+	}
+	if deltas[1].Orders != 1 || !deltas[1].Volume().Equal(decimal.NewFromInt(1)) {
+		t.Errorf("second limit should be partially filled: %+v", deltas[1])
+	}
+}
+
+func TestOrderbookMatchIOC(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(12), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+
+	o := &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(3), TIF: IOC}
+	trades, _ := b.Match(decimal.NewFromInt(11), o)
+	if len(trades) != 1 || !o.Remaining().Equal(decimal.NewFromInt(2)) {
+		t.Errorf("order should be partially filled: %+v", trades)
+	}
+	if b.BLength() != 0 || b.GetOrder(3) != nil {
+		t.Errorf("IOC residual should not rest")
+	}
+	if b.ALength() != 1 {
+		t.Errorf("limit out of price should not be touched")
+	}
+}
+
+func TestOrderbookMatchFOK(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(11), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(12), &Order{Id: 3, Volume: decimal.NewFromInt(1)})
+
+	o := &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(3), TIF: FOK}
+	trades, _ := b.Match(decimal.NewFromInt(11), o)
+	if len(trades) != 0 || !o.Remaining().Equal(decimal.NewFromInt(3)) {
+		t.Errorf("FOK order should be killed: %+v", trades)
+	}
+	if b.ALength() != 3 || b.BLength() != 0 {
+		t.Errorf("book should not change")
+	}
+
+	o = &Order{Id: 5, BidOrAsk: true, Volume: decimal.NewFromInt(3), TIF: FOK}
+	trades, _ = b.Match(decimal.NewFromInt(12), o)
+	if len(trades) != 3 || !o.Remaining().IsZero() {
+		t.Errorf("FOK order should be filled: %+v", trades)
+	}
+	if b.ALength() != 0 || b.BLength() != 0 {
+		t.Errorf("book should be empty")
+	}
+}
+
+func TestOrderbookPostOnlyReject(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(1)})
+
+	o := &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromInt(1), PostOnly: true}
+	if _, err := b.Match(decimal.NewFromInt(10), o); err != ErrPostOnlyCross {
+		t.Errorf("crossing post-only order should be rejected, got %v", err)
+	}
+	if b.BLength() != 0 || b.ALength() != 1 {
+		t.Errorf("book should not change")
+	}
+
+	if _, err := b.Match(decimal.NewFromInt(9), o); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !b.GetBestBid().Equal(decimal.NewFromInt(9)) {
+		t.Errorf("non-crossing post-only order should rest")
+	}
+}
+
+func TestOrderbookPostOnlySlide(t *testing.T) {
+	b := NewOrderbookWithInstrument(NewInstrument(decimal.NewFromFloat(0.5), decimal.NewFromInt(1)))
+	b.SetPostOnlyPolicy(PostOnlySlide)
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(8), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+
+	trades, err := b.Match(decimal.NewFromInt(11), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(1), PostOnly: true})
+	if err != nil || len(trades) != 0 {
+		t.Errorf("post-only order should not trade: %+v, %v", trades, err)
+	}
+	if !b.GetBestBid().Equal(decimal.NewFromFloat(9.5)) {
+		t.Errorf("bid should slide one tick below the best offer, got %s", b.GetBestBid().String())
+	}
+
+	b.Match(decimal.NewFromInt(7), &Order{Id: 4, Volume: decimal.NewFromInt(1), PostOnly: true})
+	if !b.GetBestOffer().Equal(decimal.NewFromInt(10)) || !b.GetVolumeAtAskLimit(decimal.NewFromInt(10)).Equal(decimal.NewFromInt(2)) {
+		t.Errorf("ask should slide one tick above the best bid")
+	}
+}
+
+func TestOrderbookIceberg(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(5), Peak: decimal.NewFromInt(2)})
+	b.Add(decimal.NewFromInt(10), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+
+	limit := b.Asks.Get(b.inst.Ticks(decimal.NewFromInt(10)))
+	if !limit.TotalVolume().Equal(decimal.NewFromInt(3)) || !limit.HiddenVolume().Equal(decimal.NewFromInt(3)) {
+		t.Errorf("only the peak should be displayed: %s, %s", limit.TotalVolume().String(), limit.HiddenVolume().String())
+	}
+
+	trades, _ := b.Match(decimal.NewFromInt(10), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(3)})
+	if len(trades) != 2 || trades[0].MakerId != 1 || trades[1].MakerId != 2 {
+		t.Fatalf("replenished peak should lose priority: %+v", trades)
+	}
+	if !trades[0].Volume().Equal(decimal.NewFromInt(2)) || !trades[1].Volume().Equal(decimal.NewFromInt(1)) {
+		t.Errorf("invalid trade volumes: %+v", trades)
+	}
+
+	o := b.GetOrder(1)
+	if o == nil || !o.Remaining().Equal(decimal.NewFromInt(3)) {
+		t.Fatalf("iceberg order should stay in the book")
+	}
+	if !limit.TotalVolume().Equal(decimal.NewFromInt(2)) || !limit.HiddenVolume().Equal(decimal.NewFromInt(1)) {
+		t.Errorf("peak should be replenished from the reserve")
+	}
+
+	trades, _ = b.Match(decimal.NewFromInt(10), &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(3)})
+	if len(trades) != 2 || b.GetOrder(1) != nil || b.ALength() != 0 {
+		t.Errorf("iceberg order should be filled through the reserve: %+v", trades)
+	}
+}
+
+func TestOrderbookIcebergAmend(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(5), Peak: decimal.NewFromInt(2)})
+
+	b.Amend(1, decimal.NewFromInt(10), decimal.NewFromInt(3))
+	limit := b.Asks.Get(b.inst.Ticks(decimal.NewFromInt(10)))
+	if !limit.TotalVolume().Equal(decimal.NewFromInt(2)) || !limit.HiddenVolume().Equal(decimal.NewFromInt(1)) {
+		t.Errorf("reserve should be reduced first")
+	}
+
+	b.Amend(1, decimal.NewFromInt(10), decimal.NewFromInt(1))
+	if !limit.TotalVolume().Equal(decimal.NewFromInt(1)) || !limit.HiddenVolume().IsZero() {
+		t.Errorf("displayed volume should be reduced after the reserve")
+	}
+}
+
+func TestOrderbookIcebergFOK(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(5), Peak: decimal.NewFromInt(1)})
+
+	o := &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromInt(4), TIF: FOK}
+	trades, _ := b.Match(decimal.NewFromInt(10), o)
+	if len(trades) != 4 || !o.Remaining().IsZero() {
+		t.Errorf("FOK order should be filled against the reserve: %+v", trades)
+	}
+}
+
+func TestOrderbookMinVolume(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(11), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(12), &Order{Id: 3, Volume: decimal.NewFromInt(5)})
+
+	o := &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(5), MinVolume: decimal.NewFromInt(3)}
+	trades, _ := b.Match(decimal.NewFromInt(11), o)
+	if len(trades) != 0 || b.BLength() != 0 || b.ALength() != 3 {
+		t.Errorf("order should be cancelled without trading: %+v", trades)
+	}
+
+	o = &Order{Id: 5, BidOrAsk: true, Volume: decimal.NewFromInt(5), MinVolume: decimal.NewFromInt(2)}
+	trades, _ = b.Match(decimal.NewFromInt(11), o)
+	if len(trades) != 2 || !b.GetVolumeAtBidLimit(decimal.NewFromInt(11)).Equal(decimal.NewFromInt(3)) {
+		t.Errorf("order should trade and rest: %+v", trades)
+	}
+
+	res := b.Market(&Order{Id: 6, BidOrAsk: true, Volume: decimal.NewFromInt(6), MinVolume: decimal.NewFromInt(6)})
+	if len(res.Trades) != 0 || !res.Unfilled.Equal(decimal.NewFromInt(6)) {
+		t.Errorf("market order should not be executed: %+v", res)
+	}
+}
+
+func TestOrderbookAONResting(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(3), AON: true})
+	b.Add(decimal.NewFromInt(10), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(10), &Order{Id: 3, Volume: decimal.NewFromInt(1)})
+
+	trades, _ := b.Match(decimal.NewFromInt(10), &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(2)})
+	if len(trades) != 2 || trades[0].MakerId != 2 || trades[1].MakerId != 3 {
+		t.Fatalf("all-or-none order should be skipped: %+v", trades)
+	}
+	if b.GetOrder(1) == nil || b.ALength() != 1 {
+		t.Errorf("all-or-none order should stay in the book")
+	}
+
+	o := &Order{Id: 5, BidOrAsk: true, Volume: decimal.NewFromInt(3), TIF: FOK}
+	trades, _ = b.Match(decimal.NewFromInt(10), o)
+	if len(trades) != 1 || trades[0].MakerId != 1 || b.ALength() != 0 {
+		t.Errorf("all-or-none order should be filled completely: %+v", trades)
+	}
+}
+
+func TestOrderbookAONIncoming(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(1)})
+
+	trades, _ := b.Match(decimal.NewFromInt(10), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromInt(2), AON: true})
+	if len(trades) != 0 || b.GetOrder(2) == nil || b.GetOrder(1) == nil {
+		t.Fatalf("all-or-none order should rest without trading: %+v", trades)
+	}
+
+	// the look-ahead does not change the book
+	res := b.Market(&Order{Id: 3, Volume: decimal.NewFromInt(1), TIF: FOK})
+	if len(res.Trades) != 0 || b.GetOrder(2) == nil {
+		t.Errorf("all-or-none bid should not be available for a smaller order: %+v", res.Trades)
+	}
+
+	res = b.Market(&Order{Id: 4, Volume: decimal.NewFromInt(2)})
+	if len(res.Trades) != 1 || res.Trades[0].MakerId != 2 {
+		t.Errorf("all-or-none bid should be filled: %+v", res.Trades)
+	}
+}
+
+func TestOrderbookErrors(t *testing.T) {
+	b := NewOrderbook()
+	o := &Order{Id: 1, Volume: decimal.NewFromInt(1)}
+	if err := b.Cancel(o); err != ErrUnknownOrder {
+		t.Errorf("expected ErrUnknownOrder, got %v", err)
+	}
+	if err := b.ClearBidLimit(decimal.NewFromInt(10)); err != ErrUnknownLevel {
+		t.Errorf("expected ErrUnknownLevel, got %v", err)
+	}
+	if err := b.DeleteAskLimit(decimal.NewFromInt(10)); err != ErrUnknownLevel {
+		t.Errorf("expected ErrUnknownLevel, got %v", err)
+	}
+	if _, err := b.BestBid(); err != ErrEmptySide {
+		t.Errorf("expected ErrEmptySide, got %v", err)
+	}
+
+	b.Add(decimal.NewFromInt(10), o)
+	if err := b.Cancel(o); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := b.Cancel(o); err != ErrUnknownOrder {
+		t.Errorf("expected ErrUnknownOrder for a cancelled order, got %v", err)
+	}
+
+	limit := NewLimitOrder(decimal.NewFromInt(10))
+	if err := limit.Delete(&Order{Id: 2}); err != ErrUnknownOrder {
+		t.Errorf("expected ErrUnknownOrder for a foreign order, got %v", err)
+	}
+
+	b.Add(decimal.NewFromInt(11), &Order{Id: 3, Volume: decimal.NewFromInt(1)})
+	if price, err := b.BestOffer(); err != nil || !price.Equal(decimal.NewFromInt(11)) {
+		t.Errorf("invalid best offer %s, %v", price.String(), err)
+	}
+}
+
 func benchmarkOrderbookLimitedRandomInsert(n int, b *testing.B) {
 	book := NewOrderbook()
 
@@ -157,3 +838,128 @@ func BenchmarkOrderbook10kLevelsRandomInsert(b *testing.B) {
 func BenchmarkOrderbook20kLevelsRandomInsert(b *testing.B) {
 	benchmarkOrderbookLimitedRandomInsert(20000, b)
 }
+
+// the same insertion bypassing decimal conversion at the API to compare with the tick representation cost
+func benchmarkOrderbookLimitedRandomInsertTicks(n int, b *testing.B) {
+	book := NewOrderbook()
+
+	// maximum number of levels in average is 10k
+	limitslist := make([]int64, n)
+	for i := range limitslist {
+		limitslist[i] = rand.Int63n(1e8)
+	}
+
+	// preallocate empty orders
+	orders := make([]*Order, 0, b.N)
+	for i := 0; i < b.N; i += 1 {
+		orders = append(orders, &Order{})
+	}
+
+	// measure insertion time
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 1 {
+		price := limitslist[rand.Intn(len(limitslist))]
+
+		// create a new order
+		o := orders[i]
+		o.Id = i
+		o.lots = rand.Int63n(1e8)
+		o.inst = book.inst
+		o.BidOrAsk = price < 5e7
+
+		// add to the book
+		book.add(price, o)
+	}
+}
+
+func BenchmarkOrderbook5kLevelsRandomInsertTicks(b *testing.B) {
+	benchmarkOrderbookLimitedRandomInsertTicks(5000, b)
+}
+
+func BenchmarkOrderbook10kLevelsRandomInsertTicks(b *testing.B) {
+	benchmarkOrderbookLimitedRandomInsertTicks(10000, b)
+}
+
+func BenchmarkOrderbook20kLevelsRandomInsertTicks(b *testing.B) {
+	benchmarkOrderbookLimitedRandomInsertTicks(20000, b)
+}
+
+// prefills the book with n bid levels and measures adding orders to existing levels,
+// the level lookup is a hash lookup so timing should not depend on the number of levels
+func benchmarkOrderbookExistingLevelInsert(n int, b *testing.B) {
+	book := NewOrderbook()
+	for i := 0; i < n; i += 1 {
+		book.add(int64(i), &Order{Id: -i - 1, BidOrAsk: true, lots: 1, inst: book.inst})
+	}
+
+	orders := make([]*Order, 0, b.N)
+	for i := 0; i < b.N; i += 1 {
+		orders = append(orders, &Order{Id: i, BidOrAsk: true, lots: 1, inst: book.inst})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 1 {
+		book.add(int64(rand.Intn(n)), orders[i])
+	}
+}
+
+func BenchmarkOrderbook10kLevelsExistingLevelInsert(b *testing.B) {
+	benchmarkOrderbookExistingLevelInsert(10000, b)
+}
+
+func BenchmarkOrderbook100kLevelsExistingLevelInsert(b *testing.B) {
+	benchmarkOrderbookExistingLevelInsert(100000, b)
+}
+
+func benchmarkOrderbookLevelLookup(n int, b *testing.B) {
+	book := NewOrderbook()
+	for i := 0; i < n; i += 1 {
+		book.add(int64(i), &Order{Id: i, BidOrAsk: true, lots: 1, inst: book.inst})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 1 {
+		if book.getBidLimitsCacheByPrice(int64(i%n)) == nil {
+			b.Fatalf("level %d should exist", i%n)
+		}
+	}
+}
+
+func BenchmarkOrderbook10kLevelsLookup(b *testing.B) {
+	benchmarkOrderbookLevelLookup(10000, b)
+}
+
+func BenchmarkOrderbook100kLevelsLookup(b *testing.B) {
+	benchmarkOrderbookLevelLookup(100000, b)
+}
+
+func TestOrderbookResubmit(t *testing.T) {
+	b := NewOrderbook()
+	o := &Order{Id: 1, Volume: decimal.NewFromInt(10), Peak: decimal.NewFromInt(2)}
+	b.Add(decimal.NewFromInt(10), o)
+	b.Match(decimal.NewFromInt(10), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromInt(4)})
+
+	if err := b.Add(decimal.NewFromInt(10), o); err != ErrDuplicateId {
+		t.Errorf("expected ErrDuplicateId, got %v", err)
+	}
+	if _, err := b.Match(decimal.NewFromInt(11), o); err != ErrDuplicateId {
+		t.Errorf("expected ErrDuplicateId, got %v", err)
+	}
+	if err := b.AddStop(decimal.NewFromInt(9), o); err != ErrDuplicateId {
+		t.Errorf("expected ErrDuplicateId, got %v", err)
+	}
+	if err := b.AddPegged(Peg{Ref: PegPrimary}, o); err != ErrDuplicateId {
+		t.Errorf("expected ErrDuplicateId, got %v", err)
+	}
+	if res := b.Market(o); !res.Filled.IsZero() {
+		t.Errorf("resting order should not be executed")
+	}
+	if !o.Remaining().Equal(decimal.NewFromInt(6)) {
+		t.Errorf("resting order should not be changed, remaining %v", o.Remaining())
+	}
+
+	b.Cancel(o)
+	if bids, asks := b.DepthWithHidden(1); len(bids) != 0 || len(asks) != 0 {
+		t.Errorf("book should be empty, got %v %v", bids, asks)
+	}
+}
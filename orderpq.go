@@ -0,0 +1,73 @@
+package rbt_orderbook
+
+// initial capacity of order queues, they grow on demand
+const OrderPQSize int = 64
+
+// Orders in an indexed minimum oriented queue. Orders are indexed by slots reused
+// through a free list so that a key can be changed or removed in place.
+type orderPQ struct {
+	keys   indexMinPQ
+	orders []*Order // by slot
+	free   []int
+}
+
+func newOrderPQ(size int) orderPQ {
+	return orderPQ{
+		keys: NewIndexMinPQ(size),
+	}
+}
+
+func (this *orderPQ) IsEmpty() bool {
+	return this.keys.IsEmpty()
+}
+
+// inserts the order and returns its slot
+func (this *orderPQ) insert(o *Order, key int64) int {
+	var slot int
+	if n := len(this.free); n > 0 {
+		slot = this.free[n-1]
+		this.free = this.free[:n-1]
+	} else {
+		slot = len(this.orders)
+		this.orders = append(this.orders, nil)
+		if slot+1 == cap(this.keys.keys) {
+			this.keys.grow(2 * len(this.orders))
+		}
+	}
+
+	this.orders[slot] = o
+	this.keys.Insert(slot, key)
+	return slot
+}
+
+func (this *orderPQ) change(slot int, key int64) {
+	this.keys.Change(slot, key)
+}
+
+func (this *orderPQ) delete(slot int) {
+	this.keys.Delete(slot)
+	this.orders[slot] = nil
+	this.free = append(this.free, slot)
+}
+
+// removes and returns the order with the minimal key if it is not greater than
+// the bound, nil otherwise
+func (this *orderPQ) pop(bound int64) *Order {
+	if this.keys.IsEmpty() || this.keys.Top() > bound {
+		return nil
+	}
+
+	slot := this.keys.TopIndex()
+	o := this.orders[slot]
+	this.delete(slot)
+	return o
+}
+
+// calls f for every order in the queue in slot order
+func (this *orderPQ) each(f func(o *Order, slot int)) {
+	for slot, o := range this.orders {
+		if o != nil {
+			f(o, slot)
+		}
+	}
+}
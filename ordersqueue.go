@@ -1,7 +1,12 @@
 package rbt_orderbook
 
-// Doubly linked orders queue
-// TODO: this should be compared with ring buffer queue performance
+import (
+	"fmt"
+)
+
+// Doubly linked orders queue. An order in the queue has Prev and Next links of
+// its neighbours, an order out of any queue has no links. See slabQueue for an
+// array backed alternative.
 type ordersQueue struct {
 	head *Order
 	tail *Order
@@ -20,16 +25,38 @@ func (this *ordersQueue) IsEmpty() bool {
 	return this.size == 0
 }
 
-func (this *ordersQueue) Enqueue(o *Order) {
-	tail := this.tail
-	this.tail = o
-	if tail != nil {
-		tail.Next = o
+func (this *ordersQueue) Head() *Order {
+	return this.head
+}
+
+func (this *ordersQueue) Next(o *Order) *Order {
+	return o.Next
+}
+
+// calls f for every order from head to tail
+func (this *ordersQueue) Each(f func(o *Order)) {
+	for o := this.head; o != nil; {
+		// f can unlink the order
+		next := o.Next
+		f(o)
+		o = next
 	}
-	if this.head == nil {
+}
+
+func (this *ordersQueue) Enqueue(o *Order) {
+	o.Prev = this.tail
+	o.Next = nil
+	if this.tail != nil {
+		this.tail.Next = o
+	} else {
 		this.head = o
 	}
+	this.tail = o
 	this.size++
+
+	if debug {
+		this.mustValidate()
+	}
 }
 
 func (this *ordersQueue) Dequeue() *Order {
@@ -38,33 +65,86 @@ func (this *ordersQueue) Dequeue() *Order {
 	}
 
 	head := this.head
-	if this.tail == this.head {
-		this.tail = nil
-	}
-
-	this.head = this.head.Next
-	this.size--
+	this.unlink(head)
 	return head
 }
 
 func (this *ordersQueue) Delete(o *Order) {
-	prev := o.Prev
-	next := o.Next
-	if prev != nil {
-		prev.Next = next
+	this.unlink(o)
+}
+
+// removes all orders from the queue unlinking them
+func (this *ordersQueue) Clear() {
+	for o := this.head; o != nil; {
+		next := o.Next
+		o.Next = nil
+		o.Prev = nil
+		o = next
 	}
-	if next != nil {
-		next.Prev = prev
+
+	this.head = nil
+	this.tail = nil
+	this.size = 0
+}
+
+func (this *ordersQueue) unlink(o *Order) {
+	if o.Prev != nil {
+		o.Prev.Next = o.Next
+	} else {
+		this.head = o.Next
+	}
+	if o.Next != nil {
+		o.Next.Prev = o.Prev
+	} else {
+		this.tail = o.Prev
 	}
 	o.Next = nil
 	o.Prev = nil
-
 	this.size--
 
-	if this.head == o {
-		this.head = next
+	if debug {
+		this.mustValidate()
+	}
+}
+
+// Validate walks the queue and checks its size, head and tail and links between orders
+func (this *ordersQueue) Validate() error {
+	if this.head == nil || this.tail == nil {
+		if this.head != this.tail || this.size != 0 {
+			return fmt.Errorf("orders queue of size %d has head %p and tail %p", this.size, this.head, this.tail)
+		}
+		return nil
+	}
+
+	if this.head.Prev != nil {
+		return fmt.Errorf("orders queue head %d has a previous order", this.head.Id)
 	}
-	if this.tail == o {
-		this.tail = prev
+
+	n := 1
+	for o := this.head; o != this.tail; o = o.Next {
+		if o.Next == nil {
+			return fmt.Errorf("orders queue tail %d is not reachable from head", this.tail.Id)
+		}
+		if o.Next.Prev != o {
+			return fmt.Errorf("orders queue order %d is not linked back to %d", o.Next.Id, o.Id)
+		}
+		n++
+		if n > this.size {
+			return fmt.Errorf("orders queue has more than %d orders", this.size)
+		}
+	}
+
+	if this.tail.Next != nil {
+		return fmt.Errorf("orders queue tail %d has a next order", this.tail.Id)
+	}
+	if n != this.size {
+		return fmt.Errorf("orders queue of size %d has %d orders", this.size, n)
+	}
+	return nil
+}
+
+func (this *ordersQueue) mustValidate() {
+	if err := this.Validate(); err != nil {
+		panic(err)
 	}
 }
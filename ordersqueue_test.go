@@ -1,6 +1,7 @@
 package rbt_orderbook
 
 import (
+	"github.com/shopspring/decimal"
 	"testing"
 )
 
@@ -76,3 +77,64 @@ func TestOrdersQueueMixed(t *testing.T) {
 		t.Errorf("a queue should be empty now")
 	}
 }
+
+func TestOrdersQueueDeleteMiddle(t *testing.T) {
+	q := NewOrdersQueue()
+	orders := []*Order{{Id: 0}, {Id: 1}, {Id: 2}}
+	for _, o := range orders {
+		q.Enqueue(o)
+	}
+
+	q.Delete(orders[1])
+	if err := q.Validate(); err != nil {
+		t.Fatalf("invalid queue: %v", err)
+	}
+	if q.Dequeue() != orders[0] || q.Dequeue() != orders[2] || !q.IsEmpty() {
+		t.Errorf("deleted order should be unlinked")
+	}
+	for _, o := range orders {
+		if o.Next != nil || o.Prev != nil {
+			t.Errorf("order %d should not be linked", o.Id)
+		}
+	}
+}
+
+func TestOrdersQueueValidate(t *testing.T) {
+	q := NewOrdersQueue()
+	a, b := &Order{Id: 0}, &Order{Id: 1}
+	q.Enqueue(a)
+	q.Enqueue(b)
+
+	b.Prev = nil
+	if q.Validate() == nil {
+		t.Errorf("broken back link should be detected")
+	}
+	b.Prev = a
+
+	q.size = 3
+	if q.Validate() == nil {
+		t.Errorf("invalid size should be detected")
+	}
+}
+
+func TestOrdersQueueClear(t *testing.T) {
+	l := NewLimitOrder(decimal.NewFromInt(1))
+	orders := []*Order{{Id: 0}, {Id: 1}, {Id: 2}}
+	for _, o := range orders {
+		l.Enqueue(o)
+	}
+
+	l.Clear()
+	for _, o := range orders {
+		if o.Next != nil || o.Prev != nil || o.Limit != nil {
+			t.Errorf("order %d should not be linked", o.Id)
+		}
+	}
+	if l.Size() != 0 || l.orders.Validate() != nil {
+		t.Errorf("queue should be empty")
+	}
+}
+
+func TestOrdersQueueRandom(t *testing.T) {
+	testQueueRandom(t, LinkedOrderQueue())
+}
@@ -0,0 +1,162 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Reference price of a pegged order
+type PegRef int
+
+const (
+	PegPrimary  PegRef = iota // the best price of the order side
+	PegMarket                 // the best price of the opposite side
+	PegMidpoint               // the middle of the best bid and offer
+)
+
+// Price of a pegged order relative to the best bid and offer. The offset moves
+// the price away from the reference to the passive side, below it for bids and
+// above it for asks.
+type Peg struct {
+	Ref    PegRef
+	Offset decimal.Decimal
+}
+
+// Best prices of the book not counting limits with pegged orders only
+type pegRefs struct {
+	bid, ask     int64
+	bidOk, askOk bool
+}
+
+// AddPegged adds an order which rests at the peg price and is re-priced whenever
+// the best bid or offer changes. A pegged order never crosses the book, its price
+// is kept one tick from the opposite side. Re-pricing moves the order to the back
+// of the queue at the new price.
+func (this *Orderbook) AddPegged(peg Peg, o *Order) error {
+	offset, err := this.inst.ExactTicks(peg.Offset)
+	if err != nil {
+		return err
+	}
+	if err := this.admit(o); err != nil {
+		return err
+	}
+
+	this.expire()
+	o.pegged = true
+	o.peg = peg
+	o.pegOffset = offset
+
+	price, ok := this.pegPrice(o, this.bestUnpegged())
+	if !ok {
+		o.pegged = false
+		return ErrNoReferencePrice
+	}
+
+	err = this.add(price, o)
+	this.trigger()
+	return err
+}
+
+// adds a pegged order entering the book to the list
+func (this *Orderbook) listPeg(o *Order) {
+	if !o.pegged {
+		return
+	}
+
+	if n := len(this.pegsFree); n > 0 {
+		o.pegSlot = this.pegsFree[n-1]
+		this.pegsFree = this.pegsFree[:n-1]
+		this.pegs[o.pegSlot] = o
+	} else {
+		o.pegSlot = len(this.pegs)
+		this.pegs = append(this.pegs, o)
+	}
+	o.pegging = true
+}
+
+// removes a pegged order leaving the book from the list
+func (this *Orderbook) unlistPeg(o *Order) {
+	if !o.pegging {
+		return
+	}
+
+	o.pegging = false
+	this.pegs[o.pegSlot] = nil
+	this.pegsFree = append(this.pegsFree, o.pegSlot)
+}
+
+// moves pegged orders to their peg prices if the reference prices changed
+func (this *Orderbook) repeg() {
+	if len(this.pegs) == len(this.pegsFree) {
+		return
+	}
+
+	refs := this.bestUnpegged()
+	if refs == this.pegRefs {
+		return
+	}
+	this.pegRefs = refs
+
+	for _, o := range this.pegs {
+		if o == nil {
+			continue
+		}
+
+		price, ok := this.pegPrice(o, refs)
+		if ok && price != o.Limit.ticks {
+			this.unrest(o)
+			this.rest(price, o)
+		}
+	}
+}
+
+// returns the best prices not counting limits with pegged orders only
+func (this *Orderbook) bestUnpegged() pegRefs {
+	var refs pegRefs
+	refs.bid, refs.bidOk = this.unpegged(true)
+	refs.ask, refs.askOk = this.unpegged(false)
+	return refs
+}
+
+// walks the side from the best limit to the first one with orders which are not pegged
+func (this *Orderbook) unpegged(bidOrAsk bool) (int64, bool) {
+	for limit := this.best(bidOrAsk); limit != nil; limit = this.worse(limit, bidOrAsk) {
+		if limit.Size() > limit.pegged {
+			return limit.ticks, true
+		}
+	}
+	return 0, false
+}
+
+// returns the price of a pegged order for the reference prices
+func (this *Orderbook) pegPrice(o *Order, refs pegRefs) (int64, bool) {
+	var price int64
+	switch {
+	case o.peg.Ref == PegMidpoint:
+		if !refs.bidOk || !refs.askOk {
+			return 0, false
+		}
+
+		// rounded to the passive side
+		price = (refs.bid + refs.ask) / 2
+		if !o.BidOrAsk {
+			price = (refs.bid + refs.ask + 1) / 2
+		}
+	case (o.peg.Ref == PegPrimary) == o.BidOrAsk:
+		if !refs.bidOk {
+			return 0, false
+		}
+		price = refs.bid
+	default:
+		if !refs.askOk {
+			return 0, false
+		}
+		price = refs.ask
+	}
+
+	if o.BidOrAsk {
+		price -= o.pegOffset
+	} else {
+		price += o.pegOffset
+	}
+	return this.passive(price, o.BidOrAsk), true
+}
@@ -0,0 +1,115 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestPeggedPrimary(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(12), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+
+	o := &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(2)}
+	if err := b.AddPegged(Peg{Ref: PegPrimary}, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !o.Limit.Price.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("order should join the best bid, got %s", o.Limit.Price.String())
+	}
+
+	var deltas []Delta
+	b.OnDelta(func(d Delta) { deltas = append(deltas, d) })
+
+	b.Add(decimal.NewFromInt(11), &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	if !o.Limit.Price.Equal(decimal.NewFromInt(11)) || !b.GetVolumeAtBidLimit(decimal.NewFromInt(10)).Equal(decimal.NewFromInt(1)) {
+		t.Errorf("order should follow the best bid, got %s", o.Limit.Price.String())
+	}
+	if len(deltas) != 3 || !deltas[2].Price.Equal(decimal.NewFromInt(11)) || deltas[2].Orders != 2 {
+		t.Errorf("re-pricing should publish deltas: %+v", deltas)
+	}
+
+	b.CancelById(4)
+	if !o.Limit.Price.Equal(decimal.NewFromInt(10)) || b.BLength() != 1 {
+		t.Errorf("order should go back to the best bid, got %s", o.Limit.Price.String())
+	}
+}
+
+func TestPeggedMarket(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(15), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+
+	o := &Order{Id: 3, Volume: decimal.NewFromInt(1)}
+	b.AddPegged(Peg{Ref: PegMarket, Offset: decimal.NewFromInt(1)}, o)
+	if !o.Limit.Price.Equal(decimal.NewFromInt(11)) {
+		t.Errorf("sell should be pegged above the best bid, got %s", o.Limit.Price.String())
+	}
+
+	b.Add(decimal.NewFromInt(8), &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.CancelById(1)
+	if !o.Limit.Price.Equal(decimal.NewFromInt(9)) {
+		t.Errorf("sell should follow the best bid down, got %s", o.Limit.Price.String())
+	}
+
+	// a pegged order never crosses the book
+	b.AddPegged(Peg{Ref: PegMarket}, &Order{Id: 5, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	if b.GetOrder(5).Limit.ticks != b.inst.Ticks(decimal.NewFromInt(9))-1 {
+		t.Errorf("buy should be pegged one tick below the best offer")
+	}
+}
+
+func TestPeggedMidpoint(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(14), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+
+	o := &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(1)}
+	b.AddPegged(Peg{Ref: PegMidpoint, Offset: decimal.NewFromInt(1)}, o)
+	if !o.Limit.Price.Equal(decimal.NewFromInt(11)) {
+		t.Errorf("buy should be pegged below the midpoint, got %s", o.Limit.Price.String())
+	}
+
+	b.Add(decimal.NewFromInt(13), &Order{Id: 4, Volume: decimal.NewFromInt(1)})
+	if !o.Limit.Price.Equal(decimal.NewFromFloat(10.5)) {
+		t.Errorf("buy should follow the midpoint, got %s", o.Limit.Price.String())
+	}
+}
+
+func TestPeggedFilled(t *testing.T) {
+	b := NewOrderbook()
+	if err := b.AddPegged(Peg{Ref: PegPrimary}, &Order{Id: 1, Volume: decimal.NewFromInt(1)}); err != ErrNoReferencePrice {
+		t.Errorf("expected ErrNoReferencePrice, got %v", err)
+	}
+
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(1)})
+	b.AddPegged(Peg{Ref: PegPrimary}, &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+	b.Match(decimal.NewFromInt(10), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(2)})
+	if b.ALength() != 0 || len(b.pegs) != len(b.pegsFree) {
+		t.Errorf("filled pegged order should leave the book")
+	}
+
+	b.Add(decimal.NewFromInt(11), &Order{Id: 4, Volume: decimal.NewFromInt(1)})
+	if b.ALength() != 1 {
+		t.Errorf("book should not change")
+	}
+}
+
+func TestPeggedLimitRemoved(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(8), &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(6), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	o := &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(1)}
+	b.AddPegged(Peg{Ref: PegPrimary, Offset: decimal.NewFromInt(1)}, o)
+
+	b.DeleteBidLimit(decimal.NewFromInt(10))
+	if !o.Limit.Price.Equal(decimal.NewFromInt(7)) {
+		t.Errorf("pegged order should follow the deleted best bid, got %s", o.Limit.Price.String())
+	}
+
+	b.ClearBidLimit(decimal.NewFromInt(8))
+	if !o.Limit.Price.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("pegged order should follow the cleared best bid, got %s", o.Limit.Price.String())
+	}
+}
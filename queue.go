@@ -0,0 +1,27 @@
+package rbt_orderbook
+
+// FIFO queue of orders at a limit
+type OrderQueue interface {
+	Size() int
+	IsEmpty() bool
+	Head() *Order
+	Next(o *Order) *Order // returns the order after o or nil
+	Enqueue(o *Order)
+	Dequeue() *Order
+	Delete(o *Order)
+	Clear()
+	Each(f func(o *Order))
+	Validate() error
+}
+
+// LinkedOrderQueue returns a new doubly linked orders queue
+func LinkedOrderQueue() OrderQueue {
+	q := NewOrdersQueue()
+	return &q
+}
+
+// SlabOrderQueue returns a new slab backed orders queue
+func SlabOrderQueue() OrderQueue {
+	q := NewSlabQueue()
+	return &q
+}
@@ -0,0 +1,143 @@
+package rbt_orderbook
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// compares the queue with a slice of orders after random operations
+func testQueueRandom(t *testing.T, q OrderQueue) {
+	r := rand.New(rand.NewSource(1))
+	var model []*Order
+
+	for i := 0; i < 10000; i += 1 {
+		switch op := r.Intn(10); {
+		case op < 5:
+			o := &Order{Id: i}
+			q.Enqueue(o)
+			model = append(model, o)
+		case op < 7:
+			o := q.Dequeue()
+			if len(model) == 0 {
+				if o != nil {
+					t.Fatalf("empty queue returned order %d", o.Id)
+				}
+				continue
+			}
+			if o != model[0] {
+				t.Fatalf("dequeued order %d, expected %d", o.Id, model[0].Id)
+			}
+			model = model[1:]
+		case op < 9:
+			if len(model) == 0 {
+				continue
+			}
+			k := r.Intn(len(model))
+			q.Delete(model[k])
+			model = append(model[:k], model[k+1:]...)
+		default:
+			if r.Intn(10) == 0 {
+				q.Clear()
+				model = model[:0]
+			}
+		}
+
+		if err := q.Validate(); err != nil {
+			t.Fatalf("invalid queue after %d operations: %v", i+1, err)
+		}
+		if q.Size() != len(model) {
+			t.Fatalf("queue size %d, expected %d", q.Size(), len(model))
+		}
+	}
+
+	k := 0
+	q.Each(func(o *Order) {
+		if o != model[k] {
+			t.Errorf("order %d at position %d, expected %d", o.Id, k, model[k].Id)
+		}
+		k++
+	})
+
+	k = 0
+	for o := q.Head(); o != nil; o = q.Next(o) {
+		if o != model[k] {
+			t.Errorf("order %d at position %d, expected %d", o.Id, k, model[k].Id)
+		}
+		k++
+	}
+}
+
+// random mix of orders added to the tail, cancelled at random positions and filled from the head
+func benchmarkQueueMix(newQueue func() OrderQueue, levelSize int, b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	q := newQueue()
+	orders := make([]*Order, 0, 2*levelSize)
+	for i := 0; i < levelSize; i += 1 {
+		o := &Order{Id: i}
+		q.Enqueue(o)
+		orders = append(orders, o)
+	}
+
+	// orders in the queue keep their index in the orders slice
+	position := make(map[*Order]int, 2*levelSize)
+	for i, o := range orders {
+		position[o] = i
+	}
+	remove := func(o *Order) {
+		k := position[o]
+		last := orders[len(orders)-1]
+		orders[k] = last
+		position[last] = k
+		orders = orders[:len(orders)-1]
+		delete(position, o)
+	}
+
+	ops := make([]int, 1024)
+	for i := range ops {
+		ops[i] = r.Intn(10)
+	}
+	spare := make([]*Order, 0, 2*levelSize)
+	for i := 0; i < 2*levelSize; i += 1 {
+		spare = append(spare, &Order{Id: levelSize + i})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 1 {
+		switch op := ops[i%len(ops)]; {
+		case op < 5 || len(orders) == 0:
+			if len(spare) == 0 {
+				continue
+			}
+			o := spare[len(spare)-1]
+			spare = spare[:len(spare)-1]
+			q.Enqueue(o)
+			position[o] = len(orders)
+			orders = append(orders, o)
+		case op < 8:
+			o := orders[r.Intn(len(orders))]
+			q.Delete(o)
+			remove(o)
+			spare = append(spare, o)
+		default:
+			o := q.Dequeue()
+			remove(o)
+			spare = append(spare, o)
+		}
+	}
+}
+
+func BenchmarkOrdersQueueMix10(b *testing.B) {
+	benchmarkQueueMix(LinkedOrderQueue, 10, b)
+}
+
+func BenchmarkOrdersQueueMix1k(b *testing.B) {
+	benchmarkQueueMix(LinkedOrderQueue, 1000, b)
+}
+
+func BenchmarkSlabQueueMix10(b *testing.B) {
+	benchmarkQueueMix(SlabOrderQueue, 10, b)
+}
+
+func BenchmarkSlabQueueMix1k(b *testing.B) {
+	benchmarkQueueMix(SlabOrderQueue, 1000, b)
+}
@@ -2,7 +2,7 @@ package rbt_orderbook
 
 import (
 	"fmt"
-	"github.com/shopspring/decimal"
+	"math"
 )
 
 // A self-balancing Binary Search Tree with 2*lgN worst case garantees for
@@ -10,7 +10,7 @@ import (
 // Average runtine for search-based operations estimated as 1*lgN
 
 type nodeRedBlack struct {
-	Key   decimal.Decimal
+	Key   int64
 	Value *LimitOrder
 	Next  *nodeRedBlack
 	Prev  *nodeRedBlack
@@ -25,12 +25,39 @@ type redBlackBST struct {
 	root *nodeRedBlack
 	minC *nodeRedBlack // cached min/max keys for O(1) access
 	maxC *nodeRedBlack
+	free *nodeRedBlack // deleted nodes linked by the right link
+	last *nodeRedBlack // node returned by Higher or Lower to walk keys in O(1)
 }
 
 func NewRedBlackBST() redBlackBST {
 	return redBlackBST{}
 }
 
+// returns a node from the free list, allocating a chunk of nodes if it is empty
+func (t *redBlackBST) newNode() *nodeRedBlack {
+	if t.free == nil {
+		chunk := make([]nodeRedBlack, ArenaChunkSize)
+		for i := range chunk {
+			chunk[i].right = t.free
+			t.free = &chunk[i]
+		}
+	}
+
+	n := t.free
+	t.free = n.right
+	n.right = nil
+	return n
+}
+
+// puts a node removed from the tree to the free list
+func (t *redBlackBST) freeNode(n *nodeRedBlack) {
+	if t.last == n {
+		t.last = nil
+	}
+	*n = nodeRedBlack{right: t.free}
+	t.free = n
+}
+
 func (t *redBlackBST) Size() int {
 	return t.size(t.root)
 }
@@ -53,31 +80,31 @@ func (t *redBlackBST) panicIfEmpty() {
 	}
 }
 
-func (t *redBlackBST) Contains(key decimal.Decimal) bool {
+func (t *redBlackBST) Contains(key int64) bool {
 	return t.get(t.root, key) != nil
 }
 
-func (t *redBlackBST) Get(key decimal.Decimal) *LimitOrder {
+func (t *redBlackBST) Get(key int64) *LimitOrder {
 	t.panicIfEmpty()
 
 	x := t.get(t.root, key)
 	if x == nil {
-		panic(fmt.Sprintf("key %+v does not exist", key.Round(8)))
+		panic(fmt.Sprintf("key %d does not exist", key))
 	}
 
 	return x.Value
 }
 
-func (t *redBlackBST) get(n *nodeRedBlack, key decimal.Decimal) *nodeRedBlack {
+func (t *redBlackBST) get(n *nodeRedBlack, key int64) *nodeRedBlack {
 	if n == nil {
 		return nil
 	}
 
-	if n.Key.Equal(key) {
+	if n.Key == key {
 		return n
 	}
 
-	if n.Key.GreaterThan(key) {
+	if n.Key > key {
 		return t.get(n.left, key)
 	} else {
 		return t.get(n.right, key)
@@ -140,28 +167,27 @@ func (t *redBlackBST) rotateRight(n *nodeRedBlack) *nodeRedBlack {
 	return x
 }
 
-func (t *redBlackBST) Put(key decimal.Decimal, value *LimitOrder) {
+func (t *redBlackBST) Put(key int64, value *LimitOrder) {
 	t.root = t.put(t.root, key, value)
 
 	// keeping root black
 	t.root.isRed = false
 }
 
-func (t *redBlackBST) put(n *nodeRedBlack, key decimal.Decimal, value *LimitOrder) *nodeRedBlack {
+func (t *redBlackBST) put(n *nodeRedBlack, key int64, value *LimitOrder) *nodeRedBlack {
 	if n == nil {
 		// search miss, creating a new node with a red link as a part of 3- or 4-node
-		n := &nodeRedBlack{
-			Value: value,
-			Key:   key,
-			size:  1,
-			isRed: true,
-		}
+		n := t.newNode()
+		n.Value = value
+		n.Key = key
+		n.size = 1
+		n.isRed = true
 
-		if t.minC == nil || key.LessThan(t.minC.Key) {
+		if t.minC == nil || key < t.minC.Key {
 			// new min
 			t.minC = n
 		}
-		if t.maxC == nil || key.GreaterThan(t.maxC.Key) {
+		if t.maxC == nil || key > t.maxC.Key {
 			// new max
 			t.maxC = n
 		}
@@ -175,7 +201,7 @@ func (t *redBlackBST) put(n *nodeRedBlack, key decimal.Decimal, value *LimitOrde
 		return n
 	}
 
-	if n.Key.GreaterThan(key) {
+	if n.Key > key {
 		left := n.left
 		n.left = t.put(n.left, key, value)
 		if left == nil {
@@ -291,7 +317,7 @@ func (t *redBlackBST) is23(n *nodeRedBlack) bool {
 	return t.is23(n.left) && t.is23(n.right)
 }
 
-func (t *redBlackBST) Min() decimal.Decimal {
+func (t *redBlackBST) Min() int64 {
 	t.panicIfEmpty()
 	return t.minC.Key
 }
@@ -314,7 +340,7 @@ func (t *redBlackBST) min(n *nodeRedBlack) *nodeRedBlack {
 	return t.min(n.left)
 }
 
-func (t *redBlackBST) Max() decimal.Decimal {
+func (t *redBlackBST) Max() int64 {
 	t.panicIfEmpty()
 	return t.maxC.Key
 }
@@ -337,29 +363,29 @@ func (t *redBlackBST) max(n *nodeRedBlack) *nodeRedBlack {
 	return t.max(n.right)
 }
 
-func (t *redBlackBST) Floor(key decimal.Decimal) decimal.Decimal {
+func (t *redBlackBST) Floor(key int64) int64 {
 	t.panicIfEmpty()
 
 	floor := t.floor(t.root, key)
 	if floor == nil {
-		panic(fmt.Sprintf("there are no keys <= %+v", key.Round(8)))
+		panic(fmt.Sprintf("there are no keys <= %d", key))
 	}
 
 	return floor.Key
 }
 
-func (t *redBlackBST) floor(n *nodeRedBlack, key decimal.Decimal) *nodeRedBlack {
+func (t *redBlackBST) floor(n *nodeRedBlack, key int64) *nodeRedBlack {
 	if n == nil {
 		// search miss
 		return nil
 	}
 
-	if n.Key.Equal(key) {
+	if n.Key == key {
 		// search hit
 		return n
 	}
 
-	if n.Key.GreaterThan(key) {
+	if n.Key > key {
 		// floor must be in the left sub-tree
 		return t.floor(n.left, key)
 	}
@@ -373,18 +399,18 @@ func (t *redBlackBST) floor(n *nodeRedBlack, key decimal.Decimal) *nodeRedBlack
 	return n
 }
 
-func (t *redBlackBST) Ceiling(key decimal.Decimal) decimal.Decimal {
+func (t *redBlackBST) Ceiling(key int64) int64 {
 	t.panicIfEmpty()
 
 	ceiling := t.ceiling(t.root, key)
 	if ceiling == nil {
-		panic(fmt.Sprintf("there are no keys >= %+v", key.Round(8)))
+		panic(fmt.Sprintf("there are no keys >= %d", key))
 	}
 
 	return ceiling.Key
 }
 
-func (t *redBlackBST) ceiling(n *nodeRedBlack, key decimal.Decimal) *nodeRedBlack {
+func (t *redBlackBST) ceiling(n *nodeRedBlack, key int64) *nodeRedBlack {
 	if n == nil {
 		// search miss
 		return nil
@@ -395,7 +421,7 @@ func (t *redBlackBST) ceiling(n *nodeRedBlack, key decimal.Decimal) *nodeRedBlac
 		return n
 	}
 
-	if n.Key.LessThan(key) {
+	if n.Key < key {
 		// ceiling must be in the right sub-tree
 		return t.ceiling(n.right, key)
 	}
@@ -409,7 +435,38 @@ func (t *redBlackBST) ceiling(n *nodeRedBlack, key decimal.Decimal) *nodeRedBlac
 	return n
 }
 
-func (t *redBlackBST) Select(k int) decimal.Decimal {
+// returns the value of the smallest key greater than key or nil
+func (t *redBlackBST) Higher(key int64) *LimitOrder {
+	var n *nodeRedBlack
+	if t.last != nil && t.last.Key == key {
+		// walking from the last returned node
+		n = t.last.Next
+	} else if key < math.MaxInt64 {
+		n = t.ceiling(t.root, key+1)
+	}
+	return t.visit(n)
+}
+
+// returns the value of the largest key less than key or nil
+func (t *redBlackBST) Lower(key int64) *LimitOrder {
+	var n *nodeRedBlack
+	if t.last != nil && t.last.Key == key {
+		n = t.last.Prev
+	} else if key > math.MinInt64 {
+		n = t.floor(t.root, key-1)
+	}
+	return t.visit(n)
+}
+
+func (t *redBlackBST) visit(n *nodeRedBlack) *LimitOrder {
+	t.last = n
+	if n == nil {
+		return nil
+	}
+	return n.Value
+}
+
+func (t *redBlackBST) Select(k int) int64 {
 	if k < 0 || k >= t.Size() {
 		panic("index out of range")
 	}
@@ -430,21 +487,21 @@ func (t *redBlackBST) selectNode(n *nodeRedBlack, k int) *nodeRedBlack {
 	return t.selectNode(n.right, k)
 }
 
-func (t *redBlackBST) Rank(key decimal.Decimal) int {
+func (t *redBlackBST) Rank(key int64) int {
 	t.panicIfEmpty()
 	return t.rank(t.root, key)
 }
 
-func (t *redBlackBST) rank(n *nodeRedBlack, key decimal.Decimal) int {
+func (t *redBlackBST) rank(n *nodeRedBlack, key int64) int {
 	if n == nil {
 		return 0
 	}
 
-	if n.Key.Equal(key) {
+	if n.Key == key {
 		return t.size(n.left)
 	}
 
-	if n.Key.GreaterThan(key) {
+	if n.Key > key {
 		return t.rank(n.left, key)
 	}
 
@@ -503,7 +560,9 @@ func (t *redBlackBST) deleteMin(n *nodeRedBlack) *nodeRedBlack {
 			t.minC = next
 		}
 
-		return n.right
+		right := n.right
+		t.freeNode(n)
+		return right
 	}
 
 	// making current node a part of 3 or 4 node by moving red link to the left
@@ -580,7 +639,9 @@ func (t *redBlackBST) deleteMax(n *nodeRedBlack) *nodeRedBlack {
 			t.maxC = prev
 		}
 
-		return n.left
+		left := n.left
+		t.freeNode(n)
+		return left
 	}
 
 	// making right left on the way from top to bottom
@@ -605,8 +666,11 @@ func (t *redBlackBST) deleteMax(n *nodeRedBlack) *nodeRedBlack {
 	return n
 }
 
-func (t *redBlackBST) Delete(key decimal.Decimal) {
-	t.panicIfEmpty()
+func (t *redBlackBST) Delete(key int64) error {
+	if !t.Contains(key) {
+		// a search miss would drop the subtree
+		return ErrUnknownLevel
+	}
 
 	if !t.isRed(t.root.left) && !t.isRed(t.root.right) {
 		t.root.isRed = true
@@ -615,10 +679,11 @@ func (t *redBlackBST) Delete(key decimal.Decimal) {
 	if !t.IsEmpty() {
 		t.root.isRed = false
 	}
+	return nil
 }
 
-func (t *redBlackBST) delete(n *nodeRedBlack, key decimal.Decimal) *nodeRedBlack {
-	if n.Key.GreaterThan(key) {
+func (t *redBlackBST) delete(n *nodeRedBlack, key int64) *nodeRedBlack {
+	if n.Key > key {
 		if n.left == nil {
 			// search miss
 			return nil
@@ -636,7 +701,7 @@ func (t *redBlackBST) delete(n *nodeRedBlack, key decimal.Decimal) *nodeRedBlack
 		if t.isRed(n.left) {
 			n = t.rotateRight(n)
 		}
-		if n.Key.Equal(key) && n.right == nil {
+		if n.Key == key && n.right == nil {
 			// search hit and we don't have right sub-tree
 
 			// updating linked list
@@ -658,6 +723,7 @@ func (t *redBlackBST) delete(n *nodeRedBlack, key decimal.Decimal) *nodeRedBlack
 				t.minC = next
 			}
 
+			t.freeNode(n)
 			return nil
 		}
 
@@ -666,11 +732,15 @@ func (t *redBlackBST) delete(n *nodeRedBlack, key decimal.Decimal) *nodeRedBlack
 		}
 		// h.right or one of its children red make
 
-		if n.Key.Equal(key) {
+		if n.Key == key {
 			// search hit, replacing the node with a successor
 			rightMin := t.min(n.right)
 			n.Key = rightMin.Key
 			n.Value = rightMin.Value
+			if t.maxC == rightMin {
+				// the successor node is freed, the max is moved with its key
+				t.maxC = n
+			}
 			n.right = t.deleteMin(n.right)
 
 			// global min will be updated automatically if requied,
@@ -700,29 +770,29 @@ func (t *redBlackBST) delete(n *nodeRedBlack, key decimal.Decimal) *nodeRedBlack
 	return n
 }
 
-func (t *redBlackBST) Keys(lo, hi decimal.Decimal) []decimal.Decimal {
-	if lo.LessThan(t.Min()) || hi.GreaterThan(t.Max()) {
-		panic("keys out of range")
+func (t *redBlackBST) Keys(lo, hi int64) ([]int64, error) {
+	if t.IsEmpty() || lo < t.Min() || hi > t.Max() {
+		return nil, ErrOutOfRange
 	}
 
-	return t.keys(t.root, lo, hi)
+	return t.keys(t.root, lo, hi), nil
 }
 
-func (t *redBlackBST) keys(n *nodeRedBlack, lo, hi decimal.Decimal) []decimal.Decimal {
+func (t *redBlackBST) keys(n *nodeRedBlack, lo, hi int64) []int64 {
 	if n == nil {
 		return nil
 	}
 
-	if n.Key.LessThan(lo) {
+	if n.Key < lo {
 		return t.keys(n.right, lo, hi)
-	} else if n.Key.GreaterThan(hi) {
+	} else if n.Key > hi {
 		return t.keys(n.left, lo, hi)
 	}
 
 	l := t.keys(n.left, lo, hi)
 	r := t.keys(n.right, lo, hi)
 
-	keys := make([]decimal.Decimal, 0)
+	keys := make([]int64, 0)
 	if l != nil {
 		keys = append(keys, l...)
 	}
@@ -748,7 +818,7 @@ func (t *redBlackBST) print(n *nodeRedBlack) {
 	if n.isRed {
 		fmt.Printf("*")
 	}
-	fmt.Printf("%+v ", n.Key.Round(8))
+	fmt.Printf("%d ", n.Key)
 
 	t.print(n.left)
 	t.print(n.right)
@@ -18,9 +18,9 @@ func TestRedBlackEmpty(t *testing.T) {
 
 func TestRedBlackBasic(t *testing.T) {
 	st := NewRedBlackBST()
-	keys := make([]decimal.Decimal, 0)
+	keys := make([]int64, 0)
 	for i := 0; i < 10; i += 1 {
-		k := decimal.NewFromFloat(rand.Float64())
+		k := rand.Int63()
 		keys = append(keys, k)
 		st.Put(k, nil)
 	}
@@ -43,7 +43,7 @@ func TestRedBlackHeight(t *testing.T) {
 	st := NewRedBlackBST()
 	n := 100000
 	for i := 0; i < n; i += 1 {
-		k := decimal.NewFromFloat(rand.Float64())
+		k := rand.Int63()
 		st.Put(k, nil)
 	}
 
@@ -63,119 +63,119 @@ func TestRedBlackHeight(t *testing.T) {
 func TestRedBlackMinMax(t *testing.T) {
 	st := NewRedBlackBST()
 	for i := 0; i < 10; i += 1 {
-		st.Put(decimal.NewFromInt(int64(10-i)), nil)
+		st.Put(int64(10-i), nil)
 	}
 
-	min := decimal.NewFromInt(1)
-	if !st.Min().Equals(min) {
-		t.Errorf("min %s != %s", st.Min().String(), min.String())
+	min := int64(1)
+	if st.Min() != min {
+		t.Errorf("min %d != %d", st.Min(), min)
 	}
 
-	max := decimal.NewFromInt(10)
-	if !st.Max().Equals(max) {
-		t.Errorf("max %s != %s", st.Max().String(), max.String())
+	max := int64(10)
+	if st.Max() != max {
+		t.Errorf("max %d != %d", st.Max(), max)
 	}
 }
 
 func TestRedBlackMinMaxCachedOnDelete(t *testing.T) {
 	st := NewRedBlackBST()
 	for i := 0; i < 100; i += 1 {
-		st.Put(decimal.NewFromInt(int64(100-i)), nil)
+		st.Put(int64(100-i), nil)
 	}
 
-	min := decimal.NewFromInt(1)
-	if !st.Min().Equals(min) {
-		t.Errorf("min %s != %s", st.Min().String(), min.String())
+	min := int64(1)
+	if st.Min() != min {
+		t.Errorf("min %d != %d", st.Min(), min)
 	}
 
-	max := decimal.NewFromInt(100)
-	if !st.Max().Equals(max) {
-		t.Errorf("max %s != %s", st.Max().String(), max.String())
+	max := int64(100)
+	if st.Max() != max {
+		t.Errorf("max %d != %d", st.Max(), max)
 	}
 
 	st.DeleteMin()
 	st.DeleteMin()
 	for i := 3; i < 20; i += 1 {
-		st.Delete(decimal.NewFromInt(int64(i)))
+		st.Delete(int64(i))
 	}
 	st.DeleteMax()
 	st.DeleteMax()
 	for i := 98; i > 70; i -= 1 {
-		st.Delete(decimal.NewFromInt(int64(i)))
+		st.Delete(int64(i))
 	}
 
-	min = decimal.NewFromInt(20)
-	if !st.Min().Equals(min) {
-		t.Errorf("min %s != %s", st.Min().String(), min.String())
+	min = int64(20)
+	if st.Min() != min {
+		t.Errorf("min %d != %d", st.Min(), min)
 	}
 
-	max = decimal.NewFromInt(70)
-	if !st.Max().Equals(max) {
-		t.Errorf("max %s != %s", st.Max().String(), max.String())
+	max = int64(70)
+	if st.Max() != max {
+		t.Errorf("max %d != %d", st.Max(), max)
 	}
 }
 
 func TestRedBlackFloor(t *testing.T) {
 	st := NewRedBlackBST()
 	for i := 0; i < 10; i += 1 {
-		k := decimal.NewFromInt(int64(20 - 2*i))
+		k := int64(20 - 2*i)
 		st.Put(k, nil)
 	}
 
-	keymiss := decimal.NewFromFloat(3.0)
-	flmiss := decimal.NewFromFloat(2.0)
-	if !st.Floor(keymiss).Equals(flmiss) {
-		t.Errorf("floor != %s", st.Floor(keymiss).String())
+	keymiss := int64(3)
+	flmiss := int64(2)
+	if st.Floor(keymiss) != flmiss {
+		t.Errorf("floor != %d", st.Floor(keymiss))
 	}
 
-	keyhit := decimal.NewFromFloat(10.0)
-	if !st.Floor(keyhit).Equals(keyhit) {
-		t.Errorf("floor != %s", st.Floor(keyhit).String())
+	keyhit := int64(10)
+	if st.Floor(keyhit) != keyhit {
+		t.Errorf("floor != %d", st.Floor(keyhit))
 	}
 }
 
 func TestRedBlackCeiling(t *testing.T) {
 	st := NewRedBlackBST()
 	for i := 0; i < 10; i += 1 {
-		k := decimal.NewFromInt(int64(20 - 2*i))
+		k := int64(20 - 2*i)
 		st.Put(k, nil)
 	}
 
-	keymiss := decimal.NewFromFloat(3.0)
-	clmiss := decimal.NewFromFloat(4.0)
-	if !st.Ceiling(keymiss).Equals(clmiss) {
-		t.Errorf("ceiling != %s", st.Ceiling(keymiss).String())
+	keymiss := int64(3)
+	clmiss := int64(4)
+	if st.Ceiling(keymiss) != clmiss {
+		t.Errorf("ceiling != %d", st.Ceiling(keymiss))
 	}
 
-	keyhit := decimal.NewFromFloat(10.0)
-	if !st.Ceiling(keyhit).Equals(keyhit) {
-		t.Errorf("ceiling != %s", st.Ceiling(keyhit).String())
+	keyhit := int64(10)
+	if st.Ceiling(keyhit) != keyhit {
+		t.Errorf("ceiling != %d", st.Ceiling(keyhit))
 	}
 }
 
 func TestRedBlackSelect(t *testing.T) {
 	st := NewRedBlackBST()
 	for i := 0; i < 10; i += 1 {
-		k := decimal.NewFromInt(int64(10 - i))
+		k := int64(10 - i)
 		st.Put(k, nil)
 	}
 
-	key := decimal.NewFromFloat(3.0)
-	if !st.Select(2).Equals(key) {
-		t.Errorf("element with rank=2 should be %s", key.String())
+	key := int64(3)
+	if st.Select(2) != key {
+		t.Errorf("element with rank=2 should be %d", key)
 	}
 
-	key = decimal.NewFromFloat(10.0)
-	if !st.Select(9).Equals(key) {
-		t.Errorf("element with rank=9 should be %s", key.String())
+	key = int64(10)
+	if st.Select(9) != key {
+		t.Errorf("element with rank=9 should be %d", key)
 	}
 }
 
 func TestRedBlackRank(t *testing.T) {
 	st := NewRedBlackBST()
-	keys := make([]decimal.Decimal, 0)
+	keys := make([]int64, 0)
 	for i := 0; i < 10; i += 1 {
-		k := decimal.NewFromInt(int64(10 - i))
+		k := int64(10 - i)
 		keys = append(keys, k)
 		st.Put(k, nil)
 	}
@@ -183,44 +183,70 @@ func TestRedBlackRank(t *testing.T) {
 	for i := range keys {
 		k := st.Select(i)
 		if st.Rank(k) != i {
-			t.Errorf("rank of %s != %d", k.String(), i)
+			t.Errorf("rank of %d != %d", k, i)
 		}
 	}
 
-	newMax := decimal.NewFromInt(11)
+	newMax := int64(11)
 	if st.Rank(newMax) != len(keys) {
 		t.Errorf("rank of new maximum should equal to the number of nodes in the tree")
 	}
 
-	if st.Rank(newMax) != st.Rank(decimal.NewFromInt(12)) {
+	if st.Rank(newMax) != st.Rank(int64(12)) {
 		t.Errorf("rank of new maximum should not depend on the new maximum concrete value")
 	}
 }
 
+func TestRedBlackErrors(t *testing.T) {
+	st := NewRedBlackBST()
+	if err := st.Delete(1); err != ErrUnknownLevel {
+		t.Errorf("expected ErrUnknownLevel, got %v", err)
+	}
+	if _, err := st.Keys(1, 2); err != ErrOutOfRange {
+		t.Errorf("expected ErrOutOfRange, got %v", err)
+	}
+
+	for i := 0; i < 10; i += 1 {
+		st.Put(int64(2*i), nil)
+	}
+	if err := st.Delete(7); err != ErrUnknownLevel {
+		t.Errorf("expected ErrUnknownLevel, got %v", err)
+	}
+	if st.Size() != 10 || !st.IsRedBlack() {
+		t.Errorf("search miss should not change the tree")
+	}
+	if _, err := st.Keys(-1, 10); err != ErrOutOfRange {
+		t.Errorf("expected ErrOutOfRange, got %v", err)
+	}
+}
+
 func TestRedBlackKeys(t *testing.T) {
 	st := NewRedBlackBST()
 	for i := 0; i < 10; i += 1 {
-		k := decimal.NewFromInt(int64(10 - i))
+		k := int64(10 - i)
 		st.Put(k, nil)
 	}
 
-	lo := decimal.NewFromFloat(3.0)
-	hi := decimal.NewFromFloat(6.0)
-	keys := st.Keys(lo, hi)
+	lo := int64(3)
+	hi := int64(6)
+	keys, err := st.Keys(lo, hi)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 	if len(keys) != 4 {
 		t.Errorf("keys len should equal 4, %+v", keys)
 	}
 
-	if !keys[0].Equals(lo) {
-		t.Errorf("first key should be %s", lo.String())
+	if keys[0] != lo {
+		t.Errorf("first key should be %d", lo)
 	}
 
-	if !keys[len(keys)-1].Equals(hi) {
-		t.Errorf("last key should be %s", hi.String())
+	if keys[len(keys)-1] != hi {
+		t.Errorf("last key should be %d", hi)
 	}
 
 	for i := 1; i < len(keys); i += 1 {
-		if keys[i].LessThan(keys[i-1]) {
+		if keys[i] < keys[i-1] {
 			t.Errorf("non-decreasing keys order validation failed")
 		}
 	}
@@ -229,7 +255,7 @@ func TestRedBlackKeys(t *testing.T) {
 func TestRedBlackDeleteMin(t *testing.T) {
 	st := NewRedBlackBST()
 	for i := 0; i < 10; i += 1 {
-		k := decimal.NewFromInt(int64(10 - i))
+		k := int64(10 - i)
 		st.Put(k, nil)
 	}
 
@@ -238,7 +264,7 @@ func TestRedBlackDeleteMin(t *testing.T) {
 		t.Errorf("tree size should shrink")
 	}
 
-	if st.Contains(decimal.NewFromInt(1)) {
+	if st.Contains(int64(1)) {
 		t.Errorf("minimum element should be removed from the tree")
 	}
 
@@ -250,7 +276,7 @@ func TestRedBlackDeleteMin(t *testing.T) {
 func TestRedBlackDeleteMax(t *testing.T) {
 	st := NewRedBlackBST()
 	for i := 0; i < 10; i += 1 {
-		k := decimal.NewFromInt(int64(i))
+		k := int64(i)
 		st.Put(k, nil)
 	}
 
@@ -259,7 +285,7 @@ func TestRedBlackDeleteMax(t *testing.T) {
 		t.Errorf("tree size should shrink")
 	}
 
-	if st.Contains(decimal.NewFromInt(9)) {
+	if st.Contains(int64(9)) {
 		t.Errorf("maximum element should be removed from the tree")
 	}
 
@@ -271,11 +297,11 @@ func TestRedBlackDeleteMax(t *testing.T) {
 func TestRedBlackDelete(t *testing.T) {
 	st := NewRedBlackBST()
 	for i := 0; i < 10; i += 1 {
-		k := decimal.NewFromInt(int64(i))
+		k := int64(i)
 		st.Put(k, nil)
 	}
 
-	key := decimal.NewFromFloat(5.0)
+	key := int64(5)
 	st.Delete(key)
 	if st.Size() != 9 {
 		t.Errorf("tree size should shrink")
@@ -293,13 +319,13 @@ func TestRedBlackDelete(t *testing.T) {
 func TestRedBlackPutLinkedListOrder(t *testing.T) {
 	st := NewRedBlackBST()
 	for i := 0; i < 100; i += 1 {
-		k := decimal.NewFromFloat(rand.Float64())
+		k := rand.Int63()
 		st.Put(k, nil)
 	}
 
 	min := st.MinPointer()
 	for p := min; p != nil && p.Next != nil; p = p.Next {
-		if p.Next.Key.LessThan(p.Key) {
+		if p.Next.Key < p.Key {
 			t.Errorf("incorrect keys order")
 			break
 		}
@@ -310,12 +336,12 @@ func TestRedBlackPutDeleteLinkedListOrder(t *testing.T) {
 	st := NewRedBlackBST()
 	n := 1000
 	for i := 0; i < n; i += 1 {
-		k := decimal.NewFromFloat(rand.Float64())
+		k := rand.Int63()
 		st.Put(k, nil)
 	}
 
 	// deleting from both ends and in the middle 90% of the nodes
-	k := int(decimal.NewFromInt(int64(n)).Mul(decimal.NewFromFloat(0.3)).IntPart())
+	k := int(float64(n) * 0.3)
 	for i := 0; i < k; i += 1 {
 		st.DeleteMin()
 		k := st.Select(rand.Intn(st.Size()))
@@ -329,7 +355,7 @@ func TestRedBlackPutDeleteLinkedListOrder(t *testing.T) {
 
 	min := st.MinPointer()
 	for p := min; p != nil && p.Next != nil; p = p.Next {
-		if p.Next.Key.LessThan(p.Key) {
+		if p.Next.Key < p.Key {
 			t.Errorf("incorrect keys order")
 			break
 		}
@@ -340,9 +366,9 @@ func benchmarkRedBlackLimitedRandomInsertWithCaching(n int, b *testing.B) {
 	st := NewRedBlackBST()
 
 	// maximum number of levels in average is 10k
-	limitslist := make([]decimal.Decimal, n)
+	limitslist := make([]int64, n)
 	for i := range limitslist {
-		limitslist[i] = decimal.NewFromFloat(rand.Float64())
+		limitslist[i] = rand.Int63n(1e8)
 	}
 
 	// preallocate empty orders
@@ -354,7 +380,7 @@ func benchmarkRedBlackLimitedRandomInsertWithCaching(n int, b *testing.B) {
 	// measure insertion time
 	b.ResetTimer()
 
-	limitscache := make(map[decimal.Decimal]*LimitOrder)
+	limitscache := make(map[int64]*LimitOrder)
 	for i := 0; i < b.N; i += 1 {
 		// create a new order
 		o := orders[i]
@@ -374,14 +400,14 @@ func benchmarkRedBlackLimitedRandomInsertWithCaching(n int, b *testing.B) {
 			limitscache[price].Enqueue(o)
 		} else {
 			// new limit
-			l := NewLimitOrder(price)
+			l := newLimitOrder(&DefaultInstrument, price)
 			l.Enqueue(o)
 
 			// caching limit
 			limitscache[price] = &l
 
 			// inserting into tree
-			st.Put(l.Price, &l)
+			st.Put(l.ticks, &l)
 		}
 	}
 }
@@ -402,7 +428,7 @@ func TestLimitOrder_Clear(t *testing.T) {
 	type fields struct {
 		Price       decimal.Decimal
 		orders      *ordersQueue
-		totalVolume decimal.Decimal
+		totalVolume int64
 	}
 	tests := []struct {
 		name   string
@@ -426,7 +452,7 @@ func TestLimitOrder_Delete(t *testing.T) {
 	type fields struct {
 		Price       decimal.Decimal
 		orders      *ordersQueue
-		totalVolume decimal.Decimal
+		totalVolume int64
 	}
 	type args struct {
 		o *Order
@@ -454,7 +480,7 @@ func TestLimitOrder_Dequeue(t *testing.T) {
 	type fields struct {
 		Price       decimal.Decimal
 		orders      *ordersQueue
-		totalVolume decimal.Decimal
+		totalVolume int64
 	}
 	tests := []struct {
 		name   string
@@ -481,7 +507,7 @@ func TestLimitOrder_Enqueue(t *testing.T) {
 	type fields struct {
 		Price       decimal.Decimal
 		orders      *ordersQueue
-		totalVolume decimal.Decimal
+		totalVolume int64
 	}
 	type args struct {
 		o *Order
@@ -509,7 +535,7 @@ func TestLimitOrder_Size(t *testing.T) {
 	type fields struct {
 		Price       decimal.Decimal
 		orders      *ordersQueue
-		totalVolume decimal.Decimal
+		totalVolume int64
 	}
 	tests := []struct {
 		name   string
@@ -536,7 +562,7 @@ func TestLimitOrder_TotalVolume(t *testing.T) {
 	type fields struct {
 		Price       decimal.Decimal
 		orders      *ordersQueue
-		totalVolume decimal.Decimal
+		totalVolume int64
 	}
 	tests := []struct {
 		name   string
@@ -638,14 +664,14 @@ func TestNewMinPQ(t *testing.T) {
 func TestNewOrderbook(t *testing.T) {
 	tests := []struct {
 		name string
-		want Orderbook
+		want *Orderbook
 	}{
 		// TODO: Add test cases.
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewOrderbook(); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("NewOrderbook() = %v, want %v", got, tt.want)
+			if got := NewOrderbook(); !reflect.DeepEqual(&got, tt.want) {
+				t.Errorf("NewOrderbook() = %v, want %v", &got, tt.want)
 			}
 		})
 	}
@@ -687,8 +713,8 @@ func TestOrderbook_ALength(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	tests := []struct {
@@ -718,8 +744,8 @@ func TestOrderbook_Add(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	type args struct {
@@ -751,8 +777,8 @@ func TestOrderbook_BLength(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	tests := []struct {
@@ -782,8 +808,8 @@ func TestOrderbook_Cancel(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	type args struct {
@@ -814,8 +840,8 @@ func TestOrderbook_ClearAskLimit(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	type args struct {
@@ -846,8 +872,8 @@ func TestOrderbook_ClearBidLimit(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	type args struct {
@@ -878,8 +904,8 @@ func TestOrderbook_DeleteAskLimit(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	type args struct {
@@ -910,8 +936,8 @@ func TestOrderbook_DeleteBidLimit(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	type args struct {
@@ -942,8 +968,8 @@ func TestOrderbook_GetBestBid(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	tests := []struct {
@@ -973,8 +999,8 @@ func TestOrderbook_GetBestOffer(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	tests := []struct {
@@ -1004,8 +1030,8 @@ func TestOrderbook_GetVolumeAtAskLimit(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	type args struct {
@@ -1039,8 +1065,8 @@ func TestOrderbook_GetVolumeAtBidLimit(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	type args struct {
@@ -1074,12 +1100,12 @@ func TestOrderbook_clearLimit(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	type args struct {
-		price    decimal.Decimal
+		price    int64
 		bidOrAsk bool
 	}
 	tests := []struct {
@@ -1107,12 +1133,12 @@ func TestOrderbook_deleteLimit(t *testing.T) {
 	type fields struct {
 		Bids           *redBlackBST
 		Asks           *redBlackBST
-		bidLimitsCache map[decimal.Decimal]*LimitOrder
-		askLimitsCache map[decimal.Decimal]*LimitOrder
+		bidLimitsCache map[int64]*LimitOrder
+		askLimitsCache map[int64]*LimitOrder
 		pool           *sync.Pool
 	}
 	type args struct {
-		price    decimal.Decimal
+		price    int64
 		bidOrAsk bool
 	}
 	tests := []struct {
@@ -2145,14 +2171,14 @@ func Test_bst_size(t1 *testing.T) {
 
 func Test_indexMinPQ_Change(t *testing.T) {
 	type fields struct {
-		keys         []decimal.Decimal
+		keys         []int64
 		index2offset []int
 		offset2index []int
 		n            int
 	}
 	type args struct {
 		i   int
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
@@ -2176,7 +2202,7 @@ func Test_indexMinPQ_Change(t *testing.T) {
 
 func Test_indexMinPQ_Contains(t *testing.T) {
 	type fields struct {
-		keys         []decimal.Decimal
+		keys         []int64
 		index2offset []int
 		offset2index []int
 		n            int
@@ -2209,7 +2235,7 @@ func Test_indexMinPQ_Contains(t *testing.T) {
 
 func Test_indexMinPQ_DelTop(t *testing.T) {
 	type fields struct {
-		keys         []decimal.Decimal
+		keys         []int64
 		index2offset []int
 		offset2index []int
 		n            int
@@ -2238,7 +2264,7 @@ func Test_indexMinPQ_DelTop(t *testing.T) {
 
 func Test_indexMinPQ_Delete(t *testing.T) {
 	type fields struct {
-		keys         []decimal.Decimal
+		keys         []int64
 		index2offset []int
 		offset2index []int
 		n            int
@@ -2268,14 +2294,14 @@ func Test_indexMinPQ_Delete(t *testing.T) {
 
 func Test_indexMinPQ_Insert(t *testing.T) {
 	type fields struct {
-		keys         []decimal.Decimal
+		keys         []int64
 		index2offset []int
 		offset2index []int
 		n            int
 	}
 	type args struct {
 		i   int
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
@@ -2299,7 +2325,7 @@ func Test_indexMinPQ_Insert(t *testing.T) {
 
 func Test_indexMinPQ_IsEmpty(t *testing.T) {
 	type fields struct {
-		keys         []decimal.Decimal
+		keys         []int64
 		index2offset []int
 		offset2index []int
 		n            int
@@ -2328,7 +2354,7 @@ func Test_indexMinPQ_IsEmpty(t *testing.T) {
 
 func Test_indexMinPQ_Size(t *testing.T) {
 	type fields struct {
-		keys         []decimal.Decimal
+		keys         []int64
 		index2offset []int
 		offset2index []int
 		n            int
@@ -2357,7 +2383,7 @@ func Test_indexMinPQ_Size(t *testing.T) {
 
 func Test_indexMinPQ_Top(t *testing.T) {
 	type fields struct {
-		keys         []decimal.Decimal
+		keys         []int64
 		index2offset []int
 		offset2index []int
 		n            int
@@ -2365,7 +2391,7 @@ func Test_indexMinPQ_Top(t *testing.T) {
 	tests := []struct {
 		name   string
 		fields fields
-		want   decimal.Decimal
+		want   int64
 	}{
 		// TODO: Add test cases.
 	}
@@ -2386,7 +2412,7 @@ func Test_indexMinPQ_Top(t *testing.T) {
 
 func Test_indexMinPQ_TopIndex(t *testing.T) {
 	type fields struct {
-		keys         []decimal.Decimal
+		keys         []int64
 		index2offset []int
 		offset2index []int
 		n            int
@@ -2415,7 +2441,7 @@ func Test_indexMinPQ_TopIndex(t *testing.T) {
 
 func Test_indexMinPQ_checkIndex(t *testing.T) {
 	type fields struct {
-		keys         []decimal.Decimal
+		keys         []int64
 		index2offset []int
 		offset2index []int
 		n            int
@@ -2445,7 +2471,7 @@ func Test_indexMinPQ_checkIndex(t *testing.T) {
 
 func Test_indexMinPQ_sink(t *testing.T) {
 	type fields struct {
-		keys         []decimal.Decimal
+		keys         []int64
 		index2offset []int
 		offset2index []int
 		n            int
@@ -2475,7 +2501,7 @@ func Test_indexMinPQ_sink(t *testing.T) {
 
 func Test_indexMinPQ_swim(t *testing.T) {
 	type fields struct {
-		keys         []decimal.Decimal
+		keys         []int64
 		index2offset []int
 		offset2index []int
 		n            int
@@ -2505,13 +2531,13 @@ func Test_indexMinPQ_swim(t *testing.T) {
 
 func Test_minPQ_DelTop(t *testing.T) {
 	type fields struct {
-		keys []decimal.Decimal
+		keys []int64
 		n    int
 	}
 	tests := []struct {
 		name   string
 		fields fields
-		want   decimal.Decimal
+		want   int64
 	}{
 		// TODO: Add test cases.
 	}
@@ -2530,11 +2556,11 @@ func Test_minPQ_DelTop(t *testing.T) {
 
 func Test_minPQ_Insert(t *testing.T) {
 	type fields struct {
-		keys []decimal.Decimal
+		keys []int64
 		n    int
 	}
 	type args struct {
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
@@ -2556,7 +2582,7 @@ func Test_minPQ_Insert(t *testing.T) {
 
 func Test_minPQ_IsEmpty(t *testing.T) {
 	type fields struct {
-		keys []decimal.Decimal
+		keys []int64
 		n    int
 	}
 	tests := []struct {
@@ -2581,7 +2607,7 @@ func Test_minPQ_IsEmpty(t *testing.T) {
 
 func Test_minPQ_Size(t *testing.T) {
 	type fields struct {
-		keys []decimal.Decimal
+		keys []int64
 		n    int
 	}
 	tests := []struct {
@@ -2606,13 +2632,13 @@ func Test_minPQ_Size(t *testing.T) {
 
 func Test_minPQ_Top(t *testing.T) {
 	type fields struct {
-		keys []decimal.Decimal
+		keys []int64
 		n    int
 	}
 	tests := []struct {
 		name   string
 		fields fields
-		want   decimal.Decimal
+		want   int64
 	}{
 		// TODO: Add test cases.
 	}
@@ -2631,7 +2657,7 @@ func Test_minPQ_Top(t *testing.T) {
 
 func Test_minPQ_sink(t *testing.T) {
 	type fields struct {
-		keys []decimal.Decimal
+		keys []int64
 		n    int
 	}
 	type args struct {
@@ -2657,7 +2683,7 @@ func Test_minPQ_sink(t *testing.T) {
 
 func Test_minPQ_swim(t *testing.T) {
 	type fields struct {
-		keys []decimal.Decimal
+		keys []int64
 		n    int
 	}
 	type args struct {
@@ -2825,13 +2851,13 @@ func Test_redBlackBST_Ceiling(t1 *testing.T) {
 		maxC *nodeRedBlack
 	}
 	type args struct {
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
 		fields fields
 		args   args
-		want   decimal.Decimal
+		want   int64
 	}{
 		// TODO: Add test cases.
 	}
@@ -2856,7 +2882,7 @@ func Test_redBlackBST_Contains(t1 *testing.T) {
 		maxC *nodeRedBlack
 	}
 	type args struct {
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
@@ -2887,7 +2913,7 @@ func Test_redBlackBST_Delete(t1 *testing.T) {
 		maxC *nodeRedBlack
 	}
 	type args struct {
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
@@ -2963,13 +2989,13 @@ func Test_redBlackBST_Floor(t1 *testing.T) {
 		maxC *nodeRedBlack
 	}
 	type args struct {
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
 		fields fields
 		args   args
-		want   decimal.Decimal
+		want   int64
 	}{
 		// TODO: Add test cases.
 	}
@@ -2994,7 +3020,7 @@ func Test_redBlackBST_Get(t1 *testing.T) {
 		maxC *nodeRedBlack
 	}
 	type args struct {
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
@@ -3106,14 +3132,15 @@ func Test_redBlackBST_Keys(t1 *testing.T) {
 		maxC *nodeRedBlack
 	}
 	type args struct {
-		lo decimal.Decimal
-		hi decimal.Decimal
+		lo int64
+		hi int64
 	}
 	tests := []struct {
-		name   string
-		fields fields
-		args   args
-		want   []decimal.Decimal
+		name    string
+		fields  fields
+		args    args
+		want    []int64
+		wantErr bool
 	}{
 		// TODO: Add test cases.
 	}
@@ -3124,7 +3151,12 @@ func Test_redBlackBST_Keys(t1 *testing.T) {
 				minC: tt.fields.minC,
 				maxC: tt.fields.maxC,
 			}
-			if got := t.Keys(tt.args.lo, tt.args.hi); !reflect.DeepEqual(got, tt.want) {
+			got, err := t.Keys(tt.args.lo, tt.args.hi)
+			if (err != nil) != tt.wantErr {
+				t1.Errorf("Keys() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
 				t1.Errorf("Keys() = %v, want %v", got, tt.want)
 			}
 		})
@@ -3140,7 +3172,7 @@ func Test_redBlackBST_Max(t1 *testing.T) {
 	tests := []struct {
 		name   string
 		fields fields
-		want   decimal.Decimal
+		want   int64
 	}{
 		// TODO: Add test cases.
 	}
@@ -3221,7 +3253,7 @@ func Test_redBlackBST_Min(t1 *testing.T) {
 	tests := []struct {
 		name   string
 		fields fields
-		want   decimal.Decimal
+		want   int64
 	}{
 		// TODO: Add test cases.
 	}
@@ -3324,7 +3356,7 @@ func Test_redBlackBST_Put(t1 *testing.T) {
 		maxC *nodeRedBlack
 	}
 	type args struct {
-		key   decimal.Decimal
+		key   int64
 		value *LimitOrder
 	}
 	tests := []struct {
@@ -3353,7 +3385,7 @@ func Test_redBlackBST_Rank(t1 *testing.T) {
 		maxC *nodeRedBlack
 	}
 	type args struct {
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
@@ -3390,7 +3422,7 @@ func Test_redBlackBST_Select(t1 *testing.T) {
 		name   string
 		fields fields
 		args   args
-		want   decimal.Decimal
+		want   int64
 	}{
 		// TODO: Add test cases.
 	}
@@ -3443,7 +3475,7 @@ func Test_redBlackBST_ceiling(t1 *testing.T) {
 	}
 	type args struct {
 		n   *nodeRedBlack
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
@@ -3475,7 +3507,7 @@ func Test_redBlackBST_delete(t1 *testing.T) {
 	}
 	type args struct {
 		n   *nodeRedBlack
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
@@ -3597,7 +3629,7 @@ func Test_redBlackBST_floor(t1 *testing.T) {
 	}
 	type args struct {
 		n   *nodeRedBlack
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
@@ -3629,7 +3661,7 @@ func Test_redBlackBST_get(t1 *testing.T) {
 	}
 	type args struct {
 		n   *nodeRedBlack
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
@@ -3790,14 +3822,14 @@ func Test_redBlackBST_keys(t1 *testing.T) {
 	}
 	type args struct {
 		n  *nodeRedBlack
-		lo decimal.Decimal
-		hi decimal.Decimal
+		lo int64
+		hi int64
 	}
 	tests := []struct {
 		name   string
 		fields fields
 		args   args
-		want   []decimal.Decimal
+		want   []int64
 	}{
 		// TODO: Add test cases.
 	}
@@ -3999,7 +4031,7 @@ func Test_redBlackBST_put(t1 *testing.T) {
 	}
 	type args struct {
 		n     *nodeRedBlack
-		key   decimal.Decimal
+		key   int64
 		value *LimitOrder
 	}
 	tests := []struct {
@@ -4032,7 +4064,7 @@ func Test_redBlackBST_rank(t1 *testing.T) {
 	}
 	type args struct {
 		n   *nodeRedBlack
-		key decimal.Decimal
+		key int64
 	}
 	tests := []struct {
 		name   string
@@ -0,0 +1,25 @@
+package rbt_orderbook
+
+// Limits of one side of the book by price in ticks, implemented by redBlackBST
+// and priceLadder. Get, Min, Max, Floor and Ceiling panic if there is no such key.
+type bookSide interface {
+	Size() int
+	IsEmpty() bool
+	Contains(key int64) bool
+	Get(key int64) *LimitOrder
+	Put(key int64, value *LimitOrder)
+	Delete(key int64) error
+	Min() int64
+	Max() int64
+	MinValue() *LimitOrder
+	MaxValue() *LimitOrder
+	Floor(key int64) int64
+	Ceiling(key int64) int64
+	Higher(key int64) *LimitOrder // limit with the smallest key greater than key or nil
+	Lower(key int64) *LimitOrder  // limit with the largest key less than key or nil
+}
+
+func newTreeSide() bookSide {
+	t := NewRedBlackBST()
+	return &t
+}
@@ -0,0 +1,150 @@
+package rbt_orderbook
+
+import (
+	"fmt"
+)
+
+// minimal number of tombstones to compact a slab queue
+const SlabCompactionMin int = 32
+
+// Orders queue backed by a slice of slots. Every order keeps the index of its slot,
+// so a delete only leaves a tombstone in place. The slots are compacted once there
+// are more tombstones than orders.
+type slabQueue struct {
+	slots []*Order // nil for tombstones
+	head  int      // first slot with an order
+	size  int
+}
+
+func NewSlabQueue() slabQueue {
+	return slabQueue{}
+}
+
+func (this *slabQueue) Size() int {
+	return this.size
+}
+
+func (this *slabQueue) IsEmpty() bool {
+	return this.size == 0
+}
+
+func (this *slabQueue) Head() *Order {
+	if this.size == 0 {
+		return nil
+	}
+	return this.slots[this.head]
+}
+
+func (this *slabQueue) Next(o *Order) *Order {
+	for i := o.slabSlot + 1; i < len(this.slots); i += 1 {
+		if this.slots[i] != nil {
+			return this.slots[i]
+		}
+	}
+	return nil
+}
+
+// calls f for every order from head to tail, f must not change the queue
+func (this *slabQueue) Each(f func(o *Order)) {
+	for i := this.head; i < len(this.slots); i += 1 {
+		if o := this.slots[i]; o != nil {
+			f(o)
+		}
+	}
+}
+
+func (this *slabQueue) Enqueue(o *Order) {
+	o.slabSlot = len(this.slots)
+	this.slots = append(this.slots, o)
+	this.size++
+
+	if debug {
+		this.mustValidate()
+	}
+}
+
+func (this *slabQueue) Dequeue() *Order {
+	if this.size == 0 {
+		return nil
+	}
+
+	head := this.slots[this.head]
+	this.Delete(head)
+	return head
+}
+
+func (this *slabQueue) Delete(o *Order) {
+	this.slots[o.slabSlot] = nil
+	this.size--
+
+	if this.size == 0 {
+		this.Clear()
+	} else {
+		// keep the head at an order
+		for this.slots[this.head] == nil {
+			this.head++
+		}
+
+		if tombstones := len(this.slots) - this.size; tombstones >= SlabCompactionMin && tombstones > this.size {
+			this.compact()
+		}
+	}
+
+	if debug {
+		this.mustValidate()
+	}
+}
+
+func (this *slabQueue) Clear() {
+	clear(this.slots)
+	this.slots = this.slots[:0]
+	this.head = 0
+	this.size = 0
+}
+
+// moves the orders to the beginning of the slots removing tombstones
+func (this *slabQueue) compact() {
+	n := 0
+	for i := this.head; i < len(this.slots); i += 1 {
+		if o := this.slots[i]; o != nil {
+			o.slabSlot = n
+			this.slots[n] = o
+			n++
+		}
+	}
+
+	clear(this.slots[n:])
+	this.slots = this.slots[:n]
+	this.head = 0
+}
+
+// Validate checks the queue size, head and slot indexes of the orders
+func (this *slabQueue) Validate() error {
+	n := 0
+	for i, o := range this.slots {
+		if o == nil {
+			continue
+		}
+		if i < this.head {
+			return fmt.Errorf("slab queue order %d is before head %d", o.Id, this.head)
+		}
+		if o.slabSlot != i {
+			return fmt.Errorf("slab queue order %d at slot %d has slot %d", o.Id, i, o.slabSlot)
+		}
+		n++
+	}
+
+	if n != this.size {
+		return fmt.Errorf("slab queue of size %d has %d orders", this.size, n)
+	}
+	if this.size > 0 && this.slots[this.head] == nil {
+		return fmt.Errorf("slab queue head %d is a tombstone", this.head)
+	}
+	return nil
+}
+
+func (this *slabQueue) mustValidate() {
+	if err := this.Validate(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,86 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestSlabQueueDelete(t *testing.T) {
+	q := NewSlabQueue()
+	orders := []*Order{{Id: 0}, {Id: 1}, {Id: 2}}
+	for _, o := range orders {
+		q.Enqueue(o)
+	}
+
+	q.Delete(orders[0])
+	q.Delete(orders[1])
+	if q.Head() != orders[2] || q.Size() != 1 {
+		t.Errorf("head should skip tombstones")
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("invalid queue: %v", err)
+	}
+
+	q.Delete(orders[2])
+	if !q.IsEmpty() || len(q.slots) != 0 {
+		t.Errorf("empty queue should reuse its slots")
+	}
+}
+
+func TestSlabQueueCompaction(t *testing.T) {
+	q := NewSlabQueue()
+	n := 4 * SlabCompactionMin
+	orders := make([]*Order, n)
+	for i := range orders {
+		orders[i] = &Order{Id: i}
+		q.Enqueue(orders[i])
+	}
+
+	// cancel every order but each 4th one from the middle of the queue
+	for i := n - 1; i >= 0; i -= 1 {
+		if i%4 != 0 {
+			q.Delete(orders[i])
+		}
+	}
+
+	if len(q.slots) > 2*q.Size() {
+		t.Errorf("tombstones should be compacted, %d slots for %d orders", len(q.slots), q.Size())
+	}
+	if err := q.Validate(); err != nil {
+		t.Fatalf("invalid queue: %v", err)
+	}
+	for i := 0; i < n; i += 4 {
+		if o := q.Dequeue(); o != orders[i] {
+			t.Errorf("order %d should be dequeued, got %+v", i, o)
+		}
+	}
+}
+
+func TestSlabQueueRandom(t *testing.T) {
+	testQueueRandom(t, SlabOrderQueue())
+}
+
+func TestSlabQueueOrderbook(t *testing.T) {
+	b := NewOrderbook(WithOrderQueue(SlabOrderQueue))
+	for i := 1; i <= 100; i += 1 {
+		b.Add(decimal.NewFromInt(10), &Order{Id: i, Volume: decimal.NewFromInt(1)})
+	}
+	for i := 2; i <= 100; i += 2 {
+		if err := b.CancelById(i); err != nil {
+			t.Fatalf("cancel of order %d failed: %v", i, err)
+		}
+	}
+
+	trades, err := b.Match(decimal.NewFromInt(10), &Order{Id: 101, BidOrAsk: true, Volume: decimal.NewFromInt(10)})
+	if err != nil || len(trades) != 10 {
+		t.Fatalf("10 orders should be matched, got %d: %v", len(trades), err)
+	}
+	for i, trade := range trades {
+		if trade.MakerId != 2*i+1 {
+			t.Errorf("trade %d should be with order %d, got %d", i, 2*i+1, trade.MakerId)
+		}
+	}
+	if v := b.GetVolumeAtAskLimit(decimal.NewFromInt(10)); !v.Equal(decimal.NewFromInt(40)) {
+		t.Errorf("40 should remain at the limit, got %s", v.String())
+	}
+}
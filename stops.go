@@ -0,0 +1,222 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// initial number of trigger prices per stop side, grows on demand
+const StopTriggersNum int = 64
+
+// Untriggered stop orders of one side grouped by trigger price into FIFO queues.
+// Trigger prices of sell stops are negated so that the next stop to fire is
+// always on top of the minimum oriented queue. Trigger prices are indexed by
+// slots reused through a free list, a trigger price is removed once its last
+// order is fired or cancelled.
+type stopSide struct {
+	triggers indexMinPQ     // trigger keys by slot
+	queues   []*ordersQueue // by slot, kept for reuse
+	slots    map[int64]int  // by trigger key, present while the key is in the pq
+	free     []int
+	sign     int64
+}
+
+func newStopSide(sign int64) stopSide {
+	return stopSide{
+		triggers: NewIndexMinPQ(StopTriggersNum),
+		slots:    make(map[int64]int),
+		sign:     sign,
+	}
+}
+
+func (this *stopSide) push(o *Order) {
+	key := this.sign * o.stop
+	slot, ok := this.slots[key]
+	if !ok {
+		if n := len(this.free); n > 0 {
+			slot = this.free[n-1]
+			this.free = this.free[:n-1]
+		} else {
+			slot = len(this.queues)
+			queue := NewOrdersQueue()
+			this.queues = append(this.queues, &queue)
+			if slot+1 == cap(this.triggers.keys) {
+				this.triggers.grow(2 * len(this.queues))
+			}
+		}
+
+		this.slots[key] = slot
+		this.triggers.Insert(slot, key)
+	}
+	this.queues[slot].Enqueue(o)
+}
+
+// removes and returns the next stop order triggered by the last trade price or nil
+func (this *stopSide) pop(last int64) *Order {
+	if this.triggers.IsEmpty() || this.triggers.Top() > this.sign*last {
+		return nil
+	}
+
+	slot := this.triggers.TopIndex()
+	o := this.queues[slot].Dequeue()
+	this.release(slot, this.triggers.Top())
+	return o
+}
+
+func (this *stopSide) remove(o *Order) {
+	key := this.sign * o.stop
+	slot := this.slots[key]
+	this.queues[slot].Delete(o)
+	this.release(slot, key)
+}
+
+// removes the trigger key of the slot once its queue is empty
+func (this *stopSide) release(slot int, key int64) {
+	if this.queues[slot].Size() > 0 {
+		return
+	}
+
+	this.triggers.Delete(slot)
+	delete(this.slots, key)
+	this.free = append(this.free, slot)
+}
+
+// AddStop adds a stop order which enters the book as a market order once the
+// last trade price reaches the stop price: at or above it for buy orders, at or
+// below it for sell orders. A triggered order is checked the same way as by Market
+// or Match, a rejected one is reported to reject handlers.
+func (this *Orderbook) AddStop(stop decimal.Decimal, o *Order) error {
+	o.stopLimit = false
+	return this.addStop(stop, o)
+}
+
+// AddStopLimit adds a stop order which enters the book as a limit order at the
+// price once the last trade price reaches the stop price.
+func (this *Orderbook) AddStopLimit(stop, price decimal.Decimal, o *Order) error {
+	ticks, err := this.inst.ExactTicks(price)
+	if err != nil {
+		return err
+	}
+
+	o.stopLimit = true
+	o.limitPrice = ticks
+	return this.addStop(stop, o)
+}
+
+func (this *Orderbook) addStop(stop decimal.Decimal, o *Order) error {
+	ticks, err := this.inst.ExactTicks(stop)
+	if err != nil {
+		return err
+	}
+	if err := this.admit(o); err != nil {
+		return err
+	}
+
+	err = this.pushStop(ticks, o)
+
+	// the stop can be reached already
+	this.trigger()
+	return err
+}
+
+func (this *Orderbook) pushStop(stop int64, o *Order) error {
+	if this.GetOrder(o.Id) != nil || this.GetStop(o.Id) != nil {
+		return ErrDuplicateId
+	}
+
+	o.stop = stop
+	this.setStop(o)
+	if o.BidOrAsk {
+		this.buyStops.push(o)
+	} else {
+		this.sellStops.push(o)
+	}
+	return nil
+}
+
+// GetStop returns an untriggered stop order by its id or nil if there is no such order
+func (this *Orderbook) GetStop(id int) *Order {
+	this.stopsRwLock.RLock()
+	defer this.stopsRwLock.RUnlock()
+	return this.stops[id]
+}
+
+func (this *Orderbook) setStop(o *Order) {
+	this.stopsRwLock.Lock()
+	defer this.stopsRwLock.Unlock()
+	this.stops[o.Id] = o
+}
+
+func (this *Orderbook) deleteStop(o *Order) {
+	this.leave(o)
+
+	this.stopsRwLock.Lock()
+	defer this.stopsRwLock.Unlock()
+	delete(this.stops, o.Id)
+}
+
+// cancels an untriggered stop order
+func (this *Orderbook) cancelStop(o *Order) {
+	if o.trailing {
+		this.trailSide(o.trail.Ref, o.BidOrAsk).remove(o)
+	} else if o.BidOrAsk {
+		this.buyStops.remove(o)
+	} else {
+		this.sellStops.remove(o)
+	}
+	this.deleteStop(o)
+}
+
+// LastPrice returns the price of the last trade in the book, ok is false if
+// there were no trades yet
+func (this *Orderbook) LastPrice() (price decimal.Decimal, ok bool) {
+	if !this.traded {
+		return decimal.Zero, false
+	}
+	return this.inst.Price(this.last), true
+}
+
+// fires triggered stop orders one at a time. Trades of a fired order move the last
+// price and can trigger further stops, which are processed in the same loop: buy
+// stops before sell stops, the lowest buy and the highest sell trigger first, FIFO
+// within a trigger price, trailing stops after the fixed ones. Order groups are
+// settled and pegged orders are re-priced before every step.
+func (this *Orderbook) trigger() {
+	for {
+		this.settleGroups()
+		this.repeg()
+		this.follow()
+
+		var o *Order
+		if this.traded {
+			o = this.buyStops.pop(this.last)
+			if o == nil {
+				o = this.sellStops.pop(this.last)
+			}
+		}
+		if o == nil {
+			o = this.popTrailing()
+		}
+		if o == nil {
+			return
+		}
+
+		this.deleteStop(o)
+		if err := this.fire(o); err != nil {
+			this.reject(o, err)
+		}
+	}
+}
+
+// enters a triggered stop order with the same checks as Match or Market
+func (this *Orderbook) fire(o *Order) error {
+	if o.stopLimit {
+		_, err := this.match(o.limitPrice, o, nil)
+		return err
+	}
+
+	if required := o.required(); required > 0 && !this.canFill(anyPrice(o.BidOrAsk), o, required) {
+		return ErrCannotFill
+	}
+	this.market(o, decimal.Zero, false)
+	return nil
+}
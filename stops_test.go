@@ -0,0 +1,153 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestStopMarket(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(11), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+
+	if err := b.AddStop(decimal.NewFromInt(10), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(1)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.GetStop(3) == nil || b.ALength() != 2 {
+		t.Fatalf("stop should wait for a trade")
+	}
+
+	var trades []Trade
+	b.OnTrade(func(tr Trade) { trades = append(trades, tr) })
+
+	b.Market(&Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	if len(trades) != 2 || trades[1].TakerId != 3 || !trades[1].Price.Equal(decimal.NewFromInt(11)) {
+		t.Errorf("stop should be triggered by the trade: %+v", trades)
+	}
+	if b.GetStop(3) != nil || b.ALength() != 0 {
+		t.Errorf("triggered stop should be executed")
+	}
+
+	last, ok := b.LastPrice()
+	if !ok || !last.Equal(decimal.NewFromInt(11)) {
+		t.Errorf("invalid last price %s", last.String())
+	}
+}
+
+func TestStopLimit(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.AddStopLimit(decimal.NewFromInt(10), decimal.NewFromInt(12), &Order{Id: 2, Volume: decimal.NewFromInt(2)})
+
+	b.Match(decimal.NewFromInt(10), &Order{Id: 3, Volume: decimal.NewFromInt(1)})
+	if b.GetStop(2) != nil || b.GetOrder(2) == nil {
+		t.Fatalf("stop-limit residual should rest in the book")
+	}
+	if !b.GetBestOffer().Equal(decimal.NewFromInt(12)) {
+		t.Errorf("stop-limit should rest at its limit price")
+	}
+}
+
+func TestStopCascade(t *testing.T) {
+	b := NewOrderbook()
+	for i := 1; i <= 3; i += 1 {
+		b.Add(decimal.NewFromInt(int64(10-i)), &Order{Id: i, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	}
+
+	// sell stops at the same trigger fire FIFO, the first one triggers the stop at 8
+	b.AddStop(decimal.NewFromInt(8), &Order{Id: 4, Volume: decimal.NewFromInt(1)})
+	b.AddStop(decimal.NewFromInt(9), &Order{Id: 5, Volume: decimal.NewFromInt(1)})
+	b.AddStop(decimal.NewFromInt(9), &Order{Id: 6, Volume: decimal.NewFromInt(1)})
+
+	var trades []Trade
+	b.OnTrade(func(tr Trade) { trades = append(trades, tr) })
+
+	b.Add(decimal.NewFromInt(9), &Order{Id: 7, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.Match(decimal.NewFromInt(9), &Order{Id: 8, Volume: decimal.NewFromInt(1)})
+
+	expected := []struct {
+		taker int
+		maker int
+	}{
+		{8, 1},
+		{5, 7},
+		{6, 2},
+		{4, 3},
+	}
+	if len(trades) != len(expected) {
+		t.Fatalf("invalid number of trades: %+v", trades)
+	}
+	for i, e := range expected {
+		if trades[i].TakerId != e.taker || trades[i].MakerId != e.maker {
+			t.Errorf("invalid trade %d: %+v", i, trades[i])
+		}
+	}
+}
+
+func TestStopCancel(t *testing.T) {
+	b := NewOrderbook()
+	b.AddStop(decimal.NewFromInt(10), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+
+	if err := b.Add(decimal.NewFromInt(9), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)}); err != ErrDuplicateId {
+		t.Errorf("stop id should be reserved, got %v", err)
+	}
+	if err := b.CancelById(1); err != nil || b.GetStop(1) != nil {
+		t.Errorf("stop should be cancelled, got %v", err)
+	}
+
+	b.Add(decimal.NewFromInt(10), &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+	trades, _ := b.Match(decimal.NewFromInt(10), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	if len(trades) != 1 || b.BLength() != 0 {
+		t.Errorf("cancelled stop should not fire")
+	}
+}
+
+func TestStopRejected(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(11), &Order{Id: 2, Volume: decimal.NewFromInt(2)})
+	b.Add(decimal.NewFromInt(9), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+
+	rejected := map[int]error{}
+	b.OnReject(func(o *Order, err error) { rejected[o.Id] = err })
+
+	b.AddStop(decimal.NewFromInt(10), &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(5), TIF: FOK})
+	b.AddStopLimit(decimal.NewFromInt(10), decimal.NewFromInt(9), &Order{Id: 5, Volume: decimal.NewFromInt(1), PostOnly: true})
+
+	b.Match(decimal.NewFromInt(10), &Order{Id: 6, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	if rejected[4] != ErrCannotFill || rejected[5] != ErrPostOnlyCross {
+		t.Errorf("triggered stops should be rejected: %v", rejected)
+	}
+	if !b.GetVolumeAtAskLimit(decimal.NewFromInt(11)).Equal(decimal.NewFromInt(2)) || b.GetOrder(5) != nil {
+		t.Errorf("rejected stops should not change the book")
+	}
+}
+
+func TestStopTriggersReclaimed(t *testing.T) {
+	b := NewOrderbook()
+	for i := 1; i <= 1000; i += 1 {
+		b.AddStop(decimal.NewFromInt(int64(i)), &Order{Id: i, BidOrAsk: i%2 == 0, Volume: decimal.NewFromInt(1)})
+		b.CancelById(i)
+	}
+
+	for _, side := range []*stopSide{&b.buyStops, &b.sellStops} {
+		if side.triggers.Size() != 0 || len(side.slots) != 0 || len(side.queues) != 1 {
+			t.Errorf("cancelled triggers should be removed, got %d keys and %d queues", side.triggers.Size(), len(side.queues))
+		}
+	}
+
+	// a trigger price stays while it has orders
+	for i := 1; i <= 100; i += 1 {
+		b.AddStop(decimal.NewFromInt(int64(10+i%10)), &Order{Id: 2000 + i, Volume: decimal.NewFromInt(1)})
+	}
+	for i := 1; i <= 100; i += 2 {
+		b.CancelById(2000 + i)
+	}
+	if b.sellStops.triggers.Size() != 5 {
+		t.Errorf("expected 5 trigger prices, got %d", b.sellStops.triggers.Size())
+	}
+	trade(&b, 3000, 15)
+	if b.sellStops.triggers.Size() != 3 || b.GetStop(2014) == nil || b.GetStop(2016) != nil {
+		t.Errorf("stops at 15 and above should fire, %d trigger prices left", b.sellStops.triggers.Size())
+	}
+}
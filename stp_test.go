@@ -0,0 +1,108 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+// book with own asks around an ask of another owner
+func stpBook(policy STPPolicy) *Orderbook {
+	b := NewOrderbook()
+	b.SetSTPPolicy(policy)
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Owner: 7, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(10), &Order{Id: 2, Owner: 8, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(11), &Order{Id: 3, Owner: 7, Volume: decimal.NewFromInt(2)})
+	return &b
+}
+
+func TestSTPCancelNewest(t *testing.T) {
+	b := stpBook(STPCancelNewest)
+	trades, _ := b.Match(decimal.NewFromInt(11), &Order{Id: 4, Owner: 7, BidOrAsk: true, Volume: decimal.NewFromInt(3)})
+	if len(trades) != 0 || b.BLength() != 0 || b.GetOrder(1) == nil {
+		t.Errorf("incoming order should be cancelled: %+v", trades)
+	}
+
+	res := b.Market(&Order{Id: 5, Owner: 7, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	if len(res.Trades) != 0 || b.ALength() != 2 {
+		t.Errorf("market order should be cancelled: %+v", res.Trades)
+	}
+}
+
+func TestSTPCancelOldest(t *testing.T) {
+	b := stpBook(STPCancelOldest)
+	trades, _ := b.Match(decimal.NewFromInt(11), &Order{Id: 4, Owner: 7, BidOrAsk: true, Volume: decimal.NewFromInt(3)})
+	if len(trades) != 1 || trades[0].MakerId != 2 {
+		t.Errorf("only the other owner should be traded: %+v", trades)
+	}
+	if b.GetOrder(1) != nil || b.GetOrder(3) != nil || b.ALength() != 0 {
+		t.Errorf("resting orders of the owner should be cancelled")
+	}
+	if !b.GetVolumeAtBidLimit(decimal.NewFromInt(11)).Equal(decimal.NewFromInt(2)) {
+		t.Errorf("incoming order residual should rest")
+	}
+}
+
+func TestSTPCancelBoth(t *testing.T) {
+	b := stpBook(STPCancelBoth)
+	trades, _ := b.Match(decimal.NewFromInt(11), &Order{Id: 4, Owner: 7, BidOrAsk: true, Volume: decimal.NewFromInt(3)})
+	if len(trades) != 0 || b.GetOrder(1) != nil || b.GetOrder(2) == nil || b.BLength() != 0 {
+		t.Errorf("both orders should be cancelled: %+v", trades)
+	}
+}
+
+func TestSTPDecrementAndCancel(t *testing.T) {
+	b := stpBook(STPDecrementAndCancel)
+	o := &Order{Id: 4, Owner: 7, BidOrAsk: true, Volume: decimal.NewFromInt(3)}
+	trades, _ := b.Match(decimal.NewFromInt(11), o)
+	if len(trades) != 1 || trades[0].MakerId != 2 || !o.Remaining().IsZero() {
+		t.Errorf("only the other owner should be traded: %+v", trades)
+	}
+	if b.GetOrder(1) != nil || !b.GetOrder(3).Remaining().Equal(decimal.NewFromInt(1)) || b.BLength() != 0 {
+		t.Errorf("orders should be decremented by the smaller volume")
+	}
+}
+
+// asks of another owner around an ask of the owner at the same price
+func stpQueueBook(policy STPPolicy) *Orderbook {
+	b := NewOrderbook()
+	b.SetSTPPolicy(policy)
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, Owner: 2, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(10), &Order{Id: 2, Owner: 1, Volume: decimal.NewFromInt(1)})
+	b.Add(decimal.NewFromInt(10), &Order{Id: 3, Owner: 2, Volume: decimal.NewFromInt(1)})
+	return &b
+}
+
+func TestSTPFillOrKill(t *testing.T) {
+	for _, policy := range []STPPolicy{STPCancelNewest, STPCancelBoth, STPDecrementAndCancel} {
+		b := stpQueueBook(policy)
+		trades, _ := b.Match(decimal.NewFromInt(10), &Order{Id: 4, Owner: 1, BidOrAsk: true, Volume: decimal.NewFromInt(2), TIF: FOK})
+		if len(trades) != 0 || b.ALength() != 1 || b.GetVolumeAtAskLimit(decimal.NewFromInt(10)).IntPart() != 3 {
+			t.Errorf("policy %d: FOK order should not be executed: %+v", policy, trades)
+		}
+
+		res := b.Market(&Order{Id: 5, Owner: 1, BidOrAsk: true, Volume: decimal.NewFromInt(2), TIF: FOK})
+		if len(res.Trades) != 0 || b.GetVolumeAtAskLimit(decimal.NewFromInt(10)).IntPart() != 3 {
+			t.Errorf("policy %d: FOK market order should not be executed: %+v", policy, res.Trades)
+		}
+	}
+
+	b := stpQueueBook(STPCancelOldest)
+	trades, _ := b.Match(decimal.NewFromInt(10), &Order{Id: 4, Owner: 1, BidOrAsk: true, Volume: decimal.NewFromInt(2), TIF: FOK})
+	if len(trades) != 2 || trades[0].MakerId != 1 || trades[1].MakerId != 3 {
+		t.Errorf("FOK order should skip the cancelled order of the owner: %+v", trades)
+	}
+}
+
+func TestSTPMinVolume(t *testing.T) {
+	b := stpQueueBook(STPCancelNewest)
+	trades, _ := b.Match(decimal.NewFromInt(10), &Order{Id: 4, Owner: 1, BidOrAsk: true, Volume: decimal.NewFromInt(3), MinVolume: decimal.NewFromInt(2)})
+	if len(trades) != 0 || b.BLength() != 0 || b.GetVolumeAtAskLimit(decimal.NewFromInt(10)).IntPart() != 3 {
+		t.Errorf("order without its minimum volume before the owner order should be cancelled: %+v", trades)
+	}
+
+	b = stpQueueBook(STPDecrementAndCancel)
+	trades, _ = b.Match(decimal.NewFromInt(10), &Order{Id: 4, Owner: 1, BidOrAsk: true, Volume: decimal.NewFromInt(3), MinVolume: decimal.NewFromInt(2)})
+	if len(trades) != 2 {
+		t.Errorf("decremented order should still execute its minimum volume: %+v", trades)
+	}
+}
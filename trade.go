@@ -0,0 +1,23 @@
+package rbt_orderbook
+
+import "github.com/shopspring/decimal"
+
+// Single execution between a resting (maker) order and an incoming (taker) order
+type Trade struct {
+	Seq      uint64 // book-wide event sequence number
+	MakerId  int
+	TakerId  int
+	Price    decimal.Decimal // resting limit price
+	Lots     int64           // executed volume in lots
+	BidOrAsk bool            // aggressor side, true if the taker is a bid
+
+	inst *Instrument
+}
+
+// Volume returns the executed volume
+func (t Trade) Volume() decimal.Decimal {
+	return t.inst.Volume(t.Lots)
+}
+
+// Callback receiving every trade as it happens
+type TradeHandler func(Trade)
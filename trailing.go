@@ -0,0 +1,170 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Reference price a trailing stop follows and is triggered by
+type TrailRef int
+
+const (
+	TrailLast TrailRef = iota // the last trade price
+	TrailBBO                  // the best offer for buy stops, the best bid for sell stops
+)
+
+// Distance of a trailing stop trigger from its reference price. A sell stop trails
+// below the highest reference price, a buy stop above the lowest one.
+type Trail struct {
+	Ref     TrailRef
+	Offset  decimal.Decimal // fixed distance in price
+	Percent decimal.Decimal // distance in percent of the reference price, used if Offset is zero
+}
+
+// Trailing stop orders of one side and reference queued by trigger prices,
+// negated for sell stops, so that a trigger can be moved in place
+type trailSide struct {
+	triggers orderPQ
+	sign     int64
+	ref      int64 // reference price the triggers were computed for
+}
+
+func newTrailSide(sign int64) trailSide {
+	return trailSide{
+		triggers: newOrderPQ(OrderPQSize),
+		sign:     sign,
+	}
+}
+
+func (this *trailSide) push(o *Order) {
+	o.slot = this.triggers.insert(o, this.sign*o.stop)
+}
+
+func (this *trailSide) remove(o *Order) {
+	this.triggers.delete(o.slot)
+}
+
+// moves triggers towards the reference price, never away from it
+func (this *trailSide) follow(ref int64, inst *Instrument) {
+	if this.triggers.IsEmpty() || ref == this.ref {
+		return
+	}
+	this.ref = ref
+
+	this.triggers.each(func(o *Order, slot int) {
+		stop := o.trailStop(ref, inst)
+		if this.sign*stop < this.sign*o.stop {
+			o.stop = stop
+			this.triggers.change(slot, this.sign*stop)
+		}
+	})
+}
+
+// removes and returns the next stop order triggered by the reference price or nil
+func (this *trailSide) pop(ref int64) *Order {
+	return this.triggers.pop(this.sign * ref)
+}
+
+// returns the trigger price of a trailing stop for the reference price
+func (o *Order) trailStop(ref int64, inst *Instrument) int64 {
+	offset := o.trailOffset
+	if offset == 0 {
+		// rounded up to at least a tick, a zero offset would fire at once
+		distance := inst.Price(ref).Mul(o.trail.Percent).Div(decimal.NewFromInt(100))
+		offset = max(1, clampUnits(distance.Div(inst.TickSize).Ceil()))
+	}
+
+	if o.BidOrAsk {
+		return ref + offset
+	}
+	return ref - offset
+}
+
+// AddTrailingStop adds a stop order with a trigger following the reference price
+// at the trail distance. The order enters the book as a market order once the
+// reference price reaches the trigger.
+func (this *Orderbook) AddTrailingStop(trail Trail, o *Order) error {
+	if !trail.Offset.IsPositive() && !trail.Percent.IsPositive() {
+		return ErrInvalidTrail
+	}
+	if trail.Ref != TrailLast && trail.Ref != TrailBBO {
+		return ErrUnknownTrailRef
+	}
+	if this.GetOrder(o.Id) != nil || this.GetStop(o.Id) != nil {
+		return ErrDuplicateId
+	}
+
+	offset, err := this.inst.ExactTicks(trail.Offset)
+	if err != nil {
+		return err
+	}
+	if err := this.admit(o); err != nil {
+		return err
+	}
+
+	ref, ok := this.trailRef(trail.Ref, o.BidOrAsk)
+	if !ok {
+		return ErrNoReferencePrice
+	}
+
+	o.trailing = true
+	o.trail = trail
+	o.trailOffset = offset
+	o.stop = o.trailStop(ref, this.inst)
+
+	this.setStop(o)
+	this.trailSide(trail.Ref, o.BidOrAsk).push(o)
+
+	this.trigger()
+	return nil
+}
+
+func (this *Orderbook) trailSide(ref TrailRef, bidOrAsk bool) *trailSide {
+	if bidOrAsk {
+		return &this.buyTrails[ref]
+	}
+	return &this.sellTrails[ref]
+}
+
+// returns the current reference price of a trailing stop side
+func (this *Orderbook) trailRef(ref TrailRef, bidOrAsk bool) (int64, bool) {
+	if ref == TrailLast {
+		return this.last, this.traded
+	}
+
+	if bidOrAsk {
+		if this.Asks.IsEmpty() {
+			return 0, false
+		}
+		return this.Asks.Min(), true
+	}
+
+	if this.Bids.IsEmpty() {
+		return 0, false
+	}
+	return this.Bids.Max(), true
+}
+
+// moves trailing stop triggers after reference prices changed
+func (this *Orderbook) follow() {
+	for _, ref := range []TrailRef{TrailLast, TrailBBO} {
+		for _, bidOrAsk := range []bool{true, false} {
+			if price, ok := this.trailRef(ref, bidOrAsk); ok {
+				this.trailSide(ref, bidOrAsk).follow(price, this.inst)
+			}
+		}
+	}
+}
+
+// removes and returns the next triggered trailing stop or nil
+func (this *Orderbook) popTrailing() *Order {
+	for _, ref := range []TrailRef{TrailLast, TrailBBO} {
+		for _, bidOrAsk := range []bool{true, false} {
+			if price, ok := this.trailRef(ref, bidOrAsk); ok {
+				if o := this.trailSide(ref, bidOrAsk).pop(price); o != nil {
+					return o
+				}
+			}
+		}
+	}
+	return nil
+}
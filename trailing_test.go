@@ -0,0 +1,101 @@
+package rbt_orderbook
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+// trades the volume at the price between two fresh orders
+func trade(b *Orderbook, id int, price int64) {
+	b.Add(decimal.NewFromInt(price), &Order{Id: id, Volume: decimal.NewFromInt(1)})
+	b.Match(decimal.NewFromInt(price), &Order{Id: id + 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+}
+
+func TestTrailingStopLast(t *testing.T) {
+	b := NewOrderbook()
+	trade(&b, 1, 10)
+
+	o := &Order{Id: 3, Volume: decimal.NewFromInt(1)}
+	if err := b.AddTrailingStop(Trail{Ref: TrailLast, Offset: decimal.NewFromInt(2)}, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b.Add(decimal.NewFromInt(5), &Order{Id: 4, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+
+	trade(&b, 10, 12)
+	if o.stop != b.inst.Ticks(decimal.NewFromInt(10)) {
+		t.Errorf("trigger should follow the last price up, got %d", o.stop)
+	}
+
+	trade(&b, 20, 11)
+	if o.stop != b.inst.Ticks(decimal.NewFromInt(10)) || b.GetStop(3) == nil {
+		t.Errorf("trigger should not follow the last price down")
+	}
+
+	trade(&b, 30, 10)
+	if b.GetStop(3) != nil || b.BLength() != 0 {
+		t.Errorf("stop should be triggered and sold into the bid")
+	}
+}
+
+func TestTrailingStopBBOPercent(t *testing.T) {
+	b := NewOrderbook()
+	b.Add(decimal.NewFromInt(100), &Order{Id: 1, Volume: decimal.NewFromInt(1)})
+
+	o := &Order{Id: 2, BidOrAsk: true, Volume: decimal.NewFromInt(1)}
+	b.AddTrailingStop(Trail{Ref: TrailBBO, Percent: decimal.NewFromInt(10)}, o)
+	if o.stop != b.inst.Ticks(decimal.NewFromInt(110)) {
+		t.Errorf("invalid initial trigger %d", o.stop)
+	}
+
+	b.Add(decimal.NewFromInt(90), &Order{Id: 3, Volume: decimal.NewFromInt(1)})
+	if o.stop != b.inst.Ticks(decimal.NewFromInt(99)) {
+		t.Errorf("trigger should follow the best offer down, got %d", o.stop)
+	}
+
+	var trades []Trade
+	b.OnTrade(func(tr Trade) { trades = append(trades, tr) })
+
+	b.CancelById(3)
+	if len(trades) != 1 || trades[0].TakerId != 2 || !trades[0].Price.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("stop should be triggered by the best offer: %+v", trades)
+	}
+}
+
+func TestTrailingStopErrors(t *testing.T) {
+	b := NewOrderbook()
+	if err := b.AddTrailingStop(Trail{}, &Order{Id: 1}); err != ErrInvalidTrail {
+		t.Errorf("expected ErrInvalidTrail, got %v", err)
+	}
+	if err := b.AddTrailingStop(Trail{Ref: 2, Offset: decimal.NewFromInt(1)}, &Order{Id: 1, Volume: decimal.NewFromInt(1)}); err != ErrUnknownTrailRef {
+		t.Errorf("expected ErrUnknownTrailRef, got %v", err)
+	}
+	if err := b.AddTrailingStop(Trail{Offset: decimal.NewFromInt(1)}, &Order{Id: 1, Volume: decimal.NewFromInt(1)}); err != ErrNoReferencePrice {
+		t.Errorf("expected ErrNoReferencePrice, got %v", err)
+	}
+
+	b.Add(decimal.NewFromInt(10), &Order{Id: 1, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+	b.AddTrailingStop(Trail{Ref: TrailBBO, Offset: decimal.NewFromInt(1)}, &Order{Id: 2, Volume: decimal.NewFromInt(1)})
+	if err := b.CancelById(2); err != nil || b.GetStop(2) != nil {
+		t.Errorf("trailing stop should be cancelled, got %v", err)
+	}
+
+	b.CancelById(1)
+	if b.BLength() != 0 {
+		t.Errorf("cancelled stop should not fire")
+	}
+}
+
+func TestTrailingStopPercentRounding(t *testing.T) {
+	b := NewOrderbookWithInstrument(NewInstrument(decimal.NewFromInt(1), decimal.NewFromInt(1)))
+	trade(&b, 1, 101)
+	b.Add(decimal.NewFromInt(100), &Order{Id: 3, BidOrAsk: true, Volume: decimal.NewFromInt(1)})
+
+	// half a tick is rounded up to a tick below the last price
+	o := &Order{Id: 4, Volume: decimal.NewFromInt(1)}
+	if err := b.AddTrailingStop(Trail{Ref: TrailLast, Percent: decimal.NewFromFloat(0.5)}, o); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o.stop != 100 || b.GetStop(4) == nil || b.BLength() != 1 {
+		t.Errorf("stop should wait a tick below the last price, trigger %d", o.stop)
+	}
+}